@@ -0,0 +1,292 @@
+package suffixarray
+
+import (
+	"sort"
+)
+
+// Match describes an approximate occurrence of a phrase in a text.
+type Match struct {
+	// Offset is the text offset at which the occurrence begins.
+	Offset uint64
+
+	// Length is the number of text symbols covered by the occurrence.
+	Length uint64
+
+	// Distance is the number of edits needed to turn the phrase into the
+	// covered text.
+	Distance uint64
+}
+
+type byMatchOffset []Match
+
+func (x byMatchOffset) Len() int           { return len(x) }
+func (x byMatchOffset) Less(i, j int) bool { return x[i].Offset < x[j].Offset }
+func (x byMatchOffset) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+
+var _ sort.Interface = byMatchOffset(nil)
+
+type mismatchSearch struct {
+	text    *Text
+	sa      *SuffixArray
+	phrase  []uint64
+	k       uint64
+	results []Match
+}
+
+// SearchMismatches returns every offset at which the given phrase occurs in
+// the indexed text with at most k substituted symbols (i.e. at Hamming
+// distance k or less), along with the number of substitutions at each.  The
+// matches are sorted by offset.
+//
+// The search walks down the suffix array as though it were a suffix tree.
+// Each node is a range of SA indices whose suffixes share a prefix, and its
+// children are found by binary search on the symbol which follows.  A child
+// whose symbol differs from the phrase costs one mismatch; once k mismatches
+// have been spent, the remainder of the phrase is matched exactly, so ranges
+// which cannot match are pruned as early as possible.
+//
+func SearchMismatches(idx *Index, phrase string, k uint64) ([]Match, error) {
+	return SearchMismatchesSymbols(idx, stringToSymbols(phrase), k)
+}
+
+// SearchMismatchesSymbols is like SearchMismatches, but the phrase is given
+// as a list of symbols.
+func SearchMismatchesSymbols(idx *Index, phrase []uint64, k uint64) ([]Match, error) {
+	s := &mismatchSearch{
+		text:   idx.searchText(),
+		sa:     idx.sa,
+		phrase: idx.prepare(phrase),
+		k:      k,
+	}
+	if err := s.visit(0, idx.sa.Len(), 0, 0); err != nil {
+		return nil, err
+	}
+	sort.Sort(byMatchOffset(s.results))
+	return s.results, nil
+}
+
+func (s *mismatchSearch) visit(lo, hi, depth, distance uint64) error {
+	m := uint64(len(s.phrase))
+
+	if distance == s.k {
+		var err error
+		for depth < m && lo < hi {
+			lo, hi, err = narrowRange(s.text, s.sa, lo, hi, depth, s.phrase[depth])
+			if err != nil {
+				return err
+			}
+			depth++
+		}
+	}
+
+	if depth == m {
+		return s.collect(lo, hi, distance)
+	}
+
+	for lo < hi {
+		symbol, childLo, childHi, ok, err := firstChildRange(s.text, s.sa, lo, hi, depth)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		childDistance := distance
+		if symbol != s.phrase[depth] {
+			childDistance++
+		}
+		if err := s.visit(childLo, childHi, depth+1, childDistance); err != nil {
+			return err
+		}
+		lo = childHi
+	}
+	return nil
+}
+
+func (s *mismatchSearch) collect(lo, hi, distance uint64) error {
+	if lo >= hi {
+		return nil
+	}
+	iter := s.sa.Iterate(lo, hi)
+	for iter.Next() {
+		s.results = append(s.results, Match{
+			Offset:   iter.Position(),
+			Length:   uint64(len(s.phrase)),
+			Distance: distance,
+		})
+	}
+	return iter.Close()
+}
+
+type editSearch struct {
+	text   *Text
+	sa     *SuffixArray
+	phrase []uint64
+	k      uint64
+	rows   [][]uint64
+	best   map[uint64]Match
+}
+
+// SearchEditDistance returns the approximate occurrences of the given phrase
+// in the indexed text, allowing at most k insertions, deletions, and
+// substitutions in total (i.e. at Levenshtein distance k or less).
+//
+// Each match is a span of text which begins at Offset and can be turned into
+// the phrase with Distance edits.  Since a match at one offset usually
+// implies several nearby spans of similar cost, only the best span starting
+// at each offset is reported: the one with the least distance, and of those,
+// the shortest.  The matches are sorted by offset.
+//
+// Like SearchMismatches, the search walks down the suffix array as though it
+// were a suffix tree.  Each node at depth d carries one column of the edit
+// distance matrix between the phrase and the d symbols on the path to it.
+// Only the band of 2k+1 cells around the diagonal can hold a distance of k or
+// less, so each step costs O(k), and a subtree is abandoned as soon as every
+// cell in its band exceeds k.
+//
+func SearchEditDistance(idx *Index, phrase string, k uint64) ([]Match, error) {
+	return SearchEditDistanceSymbols(idx, stringToSymbols(phrase), k)
+}
+
+// SearchEditDistanceSymbols is like SearchEditDistance, but the phrase is
+// given as a list of symbols.
+func SearchEditDistanceSymbols(idx *Index, phrase []uint64, k uint64) ([]Match, error) {
+	s := &editSearch{
+		text:   idx.searchText(),
+		sa:     idx.sa,
+		phrase: idx.prepare(phrase),
+		k:      k,
+		best:   make(map[uint64]Match),
+	}
+
+	// Column 0: turning a phrase prefix of length i into the empty string
+	// takes i deletions.
+	row := s.row(0)
+	for i := uint64(0); i <= s.bandHi(0); i++ {
+		row[i] = i
+	}
+
+	if err := s.visit(0, idx.sa.Len(), 0, row); err != nil {
+		return nil, err
+	}
+
+	results := make([]Match, 0, len(s.best))
+	for _, match := range s.best {
+		results = append(results, match)
+	}
+	sort.Sort(byMatchOffset(results))
+	return results, nil
+}
+
+func (s *editSearch) row(depth uint64) []uint64 {
+	for uint64(len(s.rows)) <= depth {
+		s.rows = append(s.rows, make([]uint64, len(s.phrase)+1))
+	}
+	return s.rows[depth]
+}
+
+func (s *editSearch) bandLo(depth uint64) uint64 {
+	if depth > s.k {
+		return depth - s.k
+	}
+	return 0
+}
+
+func (s *editSearch) bandHi(depth uint64) uint64 {
+	m := uint64(len(s.phrase))
+	if depth+s.k < m {
+		return depth + s.k
+	}
+	return m
+}
+
+// cell returns row[i] if i lies within the band for the given depth, or
+// k+1 (standing in for infinity) if it does not.
+func (s *editSearch) cell(row []uint64, depth uint64, i uint64) uint64 {
+	if i < s.bandLo(depth) || i > s.bandHi(depth) {
+		return s.k + 1
+	}
+	return row[i]
+}
+
+// step computes the column for depth from the column for depth-1 and the
+// symbol on the edge between them.  Returns false if every cell exceeds k.
+func (s *editSearch) step(prev []uint64, next []uint64, depth uint64, symbol uint64) bool {
+	inf := s.k + 1
+	alive := false
+	lo, hi := s.bandLo(depth), s.bandHi(depth)
+	for i := lo; i <= hi; i++ {
+		var v uint64
+		if i == 0 {
+			v = depth
+		} else {
+			v = s.cell(prev, depth-1, i-1)
+			if s.phrase[i-1] != symbol {
+				v++
+			}
+			if x := s.cell(prev, depth-1, i) + 1; x < v {
+				v = x
+			}
+			if x := s.cell(next, depth, i-1) + 1; x < v {
+				v = x
+			}
+		}
+		if v > inf {
+			v = inf
+		}
+		if v < inf {
+			alive = true
+		}
+		next[i] = v
+	}
+	return alive
+}
+
+func (s *editSearch) visit(lo, hi, depth uint64, row []uint64) error {
+	m := uint64(len(s.phrase))
+
+	if distance := s.cell(row, depth, m); distance <= s.k {
+		if err := s.collect(lo, hi, depth, distance); err != nil {
+			return err
+		}
+	}
+
+	// Beyond this depth, even the last cell needs more than k insertions.
+	if depth >= m+s.k {
+		return nil
+	}
+
+	next := s.row(depth + 1)
+	for lo < hi {
+		symbol, childLo, childHi, ok, err := firstChildRange(s.text, s.sa, lo, hi, depth)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		if s.step(row, next, depth+1, symbol) {
+			if err := s.visit(childLo, childHi, depth+1, next); err != nil {
+				return err
+			}
+		}
+		lo = childHi
+	}
+	return nil
+}
+
+func (s *editSearch) collect(lo, hi, depth, distance uint64) error {
+	iter := s.sa.Iterate(lo, hi)
+	for iter.Next() {
+		pos := iter.Position()
+
+		// Spans are visited in order of increasing length, so an
+		// existing span of equal distance is already the shortest.
+		if match, found := s.best[pos]; !found || distance < match.Distance {
+			s.best[pos] = Match{Offset: pos, Length: depth, Distance: distance}
+		}
+	}
+	return iter.Close()
+}
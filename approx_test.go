@@ -0,0 +1,155 @@
+package suffixarray
+
+import (
+	"fmt"
+	"testing"
+)
+
+func NaiveSearchMismatches(text, phrase string, k uint64) []Match {
+	var out []Match
+	for i := 0; i+len(phrase) <= len(text); i++ {
+		distance := uint64(0)
+		for j := 0; j < len(phrase) && distance <= k; j++ {
+			if text[i+j] != phrase[j] {
+				distance++
+			}
+		}
+		if distance <= k {
+			out = append(out, Match{uint64(i), uint64(len(phrase)), distance})
+		}
+	}
+	return out
+}
+
+func TestSearchMismatches(t *testing.T) {
+	type testrow struct {
+		Phrase string
+		K      uint64
+	}
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		idx, err := BuildIndex(NewTextFromString(sampleText, opts...), opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildIndex: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for i, row := range []testrow{
+			testrow{searchPhrase, 0},
+			testrow{searchPhrase, 1},
+			testrow{searchPhrase, 2},
+			testrow{"dolor", 1},
+			testrow{"dolor sit amet", 3},
+			testrow{"Phasellus", 2},
+			testrow{"zzzz", 1},
+			testrow{"zzzz", 3},
+			testrow{"a", 0},
+		} {
+			matches, err := SearchMismatches(idx, row.Phrase, row.K)
+			if err != nil {
+				t.Errorf("[%s/%03d] SearchMismatches %q %d: error: %v", cfg.Name, i, row.Phrase, row.K, err)
+				continue
+			}
+
+			expected := fmt.Sprintf("%v", NaiveSearchMismatches(sampleText, row.Phrase, row.K))
+			actual := fmt.Sprintf("%v", matches)
+			if expected != actual {
+				t.Errorf("[%s/%03d] SearchMismatches %q %d: expected %s, got %s", cfg.Name, i, row.Phrase, row.K, expected, actual)
+			}
+		}
+
+		if err := idx.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
+
+func editDistance(a, b string) uint64 {
+	prev := make([]uint64, len(b)+1)
+	next := make([]uint64, len(b)+1)
+	for j := range prev {
+		prev[j] = uint64(j)
+	}
+	for i := 1; i <= len(a); i++ {
+		next[0] = uint64(i)
+		for j := 1; j <= len(b); j++ {
+			v := prev[j-1]
+			if a[i-1] != b[j-1] {
+				v++
+			}
+			if prev[j]+1 < v {
+				v = prev[j] + 1
+			}
+			if next[j-1]+1 < v {
+				v = next[j-1] + 1
+			}
+			next[j] = v
+		}
+		prev, next = next, prev
+	}
+	return prev[len(b)]
+}
+
+func NaiveSearchEditDistance(text, phrase string, k uint64) []Match {
+	var out []Match
+	for i := 0; i <= len(text); i++ {
+		found := false
+		var best Match
+		for j := i; j <= len(text) && j <= i+len(phrase)+int(k); j++ {
+			distance := editDistance(phrase, text[i:j])
+			if distance <= k && (!found || distance < best.Distance) {
+				best = Match{uint64(i), uint64(j - i), distance}
+				found = true
+			}
+		}
+		if found {
+			out = append(out, best)
+		}
+	}
+	return out
+}
+
+func TestSearchEditDistance(t *testing.T) {
+	type testrow struct {
+		Phrase string
+		K      uint64
+	}
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		idx, err := BuildIndex(NewTextFromString(sampleText, opts...), opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildIndex: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for i, row := range []testrow{
+			testrow{searchPhrase, 0},
+			testrow{searchPhrase, 1},
+			testrow{"dolor", 2},
+			testrow{"dolr sit amet", 2},
+			testrow{"Phasselus", 1},
+			testrow{"zzzz", 2},
+			testrow{"ab", 2},
+		} {
+			matches, err := SearchEditDistance(idx, row.Phrase, row.K)
+			if err != nil {
+				t.Errorf("[%s/%03d] SearchEditDistance %q %d: error: %v", cfg.Name, i, row.Phrase, row.K, err)
+				continue
+			}
+
+			expected := fmt.Sprintf("%v", NaiveSearchEditDistance(sampleText, row.Phrase, row.K))
+			actual := fmt.Sprintf("%v", matches)
+			if expected != actual {
+				t.Errorf("[%s/%03d] SearchEditDistance %q %d: expected %s, got %s", cfg.Name, i, row.Phrase, row.K, expected, actual)
+			}
+		}
+
+		if err := idx.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package suffixarray
+
+import (
+	"sort"
+	"sync"
+)
+
+type batchRange struct {
+	lo uint64
+	hi uint64
+}
+
+// SearchAll searches the indexed text for many phrases at once.  Returns one
+// list of offsets per phrase, in the same order as the phrases; each list is
+// the same as SearchIndex would return for that phrase.
+//
+// The phrases are searched in sorted order, which lets each search skip work
+// that the earlier ones have done:
+//
+//  - Identical phrases are searched only once.
+//  - When an earlier phrase is a prefix of this one, the search is confined
+//    to that phrase's range, and compares each suffix from the end of the
+//    earlier phrase.  Since the ranges of sorted phrases begin in
+//    nondecreasing order, it also starts no earlier than the range of the
+//    previous phrase.  Dictionary-style workloads, in which many phrases
+//    extend others, thus pay for each shared prefix only once.
+//  - Other phrases are searched for with the LCP-LR array, as by Search.
+//  - If the start of a range shows that the phrase is absent, its end is
+//    not searched for.
+//
+// Finally, rather than sorting the offsets of each phrase separately, the
+// offsets of all the phrases are sorted together by a radix sort, in time
+// linear in the total number of matches.
+//
+func SearchAll(idx *Index, phrases []string) ([][]uint64, error) {
+	return SearchAllParallel(idx, phrases, 1)
+}
+
+// SearchAllParallel is like SearchAll, but divides the sorted phrases among
+// the given number of goroutines.
+//
+// The goroutines only read the Text and SuffixArray through random access,
+// which is safe to do concurrently for both in-memory and on-disk arrays.
+// The offsets are then gathered by the calling goroutine alone.
+//
+func SearchAllParallel(idx *Index, phrases []string, workers int) ([][]uint64, error) {
+	n := len(phrases)
+	prepared := make([][]uint64, n)
+	order := make([]int, n)
+	for i, phrase := range phrases {
+		prepared[i] = idx.prepare(stringToSymbols(phrase))
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return compareSymbols(prepared[order[a]], prepared[order[b]]) < 0
+	})
+
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	ranges := make([]batchRange, n)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		chunk := order[w*n/workers : (w+1)*n/workers]
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			errs[w] = rangeSorted(idx.searchText(), idx.sa, idx.lcplr, prepared, chunk, ranges)
+		}(w)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Gather the offsets of each distinct phrase, tagged with the phrase
+	// they belong to, and sort them all at once.  Dealing them out in
+	// sorted order then leaves each phrase's list sorted.
+	var distinct []int
+	var total uint64
+	for k, i := range order {
+		if k == 0 || !sameSymbols(prepared[order[k-1]], prepared[i]) {
+			distinct = append(distinct, i)
+			total += ranges[i].hi - ranges[i].lo
+		}
+	}
+	offsets := make([]uint64, 0, total)
+	owners := make([]int, 0, total)
+	for _, i := range distinct {
+		r := ranges[i]
+		if r.lo >= r.hi {
+			continue
+		}
+		iter := idx.sa.Iterate(r.lo, r.hi)
+		for iter.Next() {
+			offsets = append(offsets, iter.Position())
+			owners = append(owners, i)
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	offsets, owners = radixSortOffsets(offsets, owners, idx.sa.Len())
+
+	results := make([][]uint64, n)
+	backing := make([]uint64, total)
+	var used uint64
+	for _, i := range distinct {
+		if count := ranges[i].hi - ranges[i].lo; count > 0 {
+			results[i] = backing[used : used : used+count]
+			used += count
+		}
+	}
+	for j, offset := range offsets {
+		results[owners[j]] = append(results[owners[j]], offset)
+	}
+	for k, i := range order {
+		if k > 0 && results[i] == nil && ranges[i].hi > ranges[i].lo {
+			results[i] = append([]uint64(nil), results[order[k-1]]...)
+		}
+	}
+	return results, nil
+}
+
+// rangeSorted finds the suffix array range of phrases[i] for each i in order,
+// which must list the phrases in sorted order, and stores it in ranges[i].
+func rangeSorted(text *Text, sa *SuffixArray, lcplr Storage, phrases [][]uint64, order []int, ranges []batchRange) error {
+	// stack holds the ranges of earlier phrases, each a prefix of the
+	// next and all prefixes of the previous phrase, along with their
+	// lengths.  The whole array is the range of the empty phrase.
+	type prefixRange struct {
+		depth uint64
+		batchRange
+	}
+	stack := []prefixRange{{0, batchRange{0, sa.Len()}}}
+	var prev []uint64
+	var floor uint64
+
+	for k, i := range order {
+		phrase := phrases[i]
+		if k > 0 && sameSymbols(prev, phrase) {
+			ranges[i] = ranges[order[k-1]]
+			continue
+		}
+
+		common := uint64(0)
+		for common < uint64(len(prev)) && common < uint64(len(phrase)) && prev[common] == phrase[common] {
+			common++
+		}
+		for stack[len(stack)-1].depth > common {
+			stack = stack[:len(stack)-1]
+		}
+		top := stack[len(stack)-1]
+
+		state := searchState{text: text, sa: sa, phrase: phrase}
+		var lo, hi, matched uint64
+		var err error
+		if top.depth == 0 {
+			// Nothing is known about the phrase, so search the
+			// whole array with the LCP-LR array, as Search does.
+			state.lcplr = lcplr
+			lo, matched, err = state.bound()
+			hi = lo
+			if err == nil && matched == uint64(len(phrase)) {
+				state.upper = true
+				hi, _, err = state.bound()
+			}
+		} else {
+			lo = top.lo
+			if floor > lo {
+				lo = floor
+			}
+			lo, matched, err = state.boundIn(lo, top.hi, top.depth)
+			hi = lo
+			if err == nil && lo < top.hi && matched == uint64(len(phrase)) {
+				state.upper = true
+				hi, _, err = state.boundIn(lo, top.hi, top.depth)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		ranges[i] = batchRange{lo, hi}
+		stack = append(stack, prefixRange{uint64(len(phrase)), ranges[i]})
+		floor = lo
+		prev = phrase
+	}
+	return nil
+}
+
+// sameSymbols returns true if a and b are the same list of symbols.
+func sameSymbols(a, b []uint64) bool {
+	return len(a) == len(b) && compareSymbols(a, b) == 0
+}
+
+// radixSortOffsets sorts offsets, each of which must be less than limit, into
+// increasing order by a least significant digit radix sort on bytes, and moves
+// owners along with them.  Returns the sorted lists, which may be either the
+// given ones or new ones.
+func radixSortOffsets(offsets []uint64, owners []int, limit uint64) ([]uint64, []int) {
+	if len(offsets) < 2 {
+		return offsets, owners
+	}
+	tmpOffsets := make([]uint64, len(offsets))
+	tmpOwners := make([]int, len(owners))
+	for shift := uint(0); shift < 64 && (shift == 0 || (limit-1)>>shift != 0); shift += 8 {
+		var start [257]int
+		for _, offset := range offsets {
+			start[(offset>>shift)&0xff+1]++
+		}
+		for b := 1; b < len(start); b++ {
+			start[b] += start[b-1]
+		}
+		for j, offset := range offsets {
+			b := (offset >> shift) & 0xff
+			tmpOffsets[start[b]] = offset
+			tmpOwners[start[b]] = owners[j]
+			start[b]++
+		}
+		offsets, tmpOffsets = tmpOffsets, offsets
+		owners, tmpOwners = tmpOwners, owners
+	}
+	return offsets, owners
+}
+
+// compareSymbols compares two lists of symbols lexicographically, returning
+// -1, 0, or +1.
+func compareSymbols(a, b []uint64) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] < b[i] {
+			return -1
+		}
+		if a[i] > b[i] {
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,108 @@
+package suffixarray
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSearchAll(t *testing.T) {
+	phrases := regexp.MustCompile(`[\pL\pN]+`).FindAllString(sampleText, -1)
+	phrases = append(phrases, "", "a", "am", "amet", "ametx", "zzz", "sit amet", "Lorem ipsum", "lorem")
+
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		idx, err := BuildIndex(NewTextFromString(sampleText, opts...), opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildIndex: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for _, workers := range []int{1, 4} {
+			results, err := SearchAllParallel(idx, phrases, workers)
+			if err != nil {
+				t.Errorf("[%s/%d] SearchAllParallel: error: %v", cfg.Name, workers, err)
+				continue
+			}
+			if len(results) != len(phrases) {
+				t.Errorf("[%s/%d] SearchAllParallel: expected %d results, got %d", cfg.Name, workers, len(phrases), len(results))
+				continue
+			}
+
+			for i, phrase := range phrases {
+				offsets, err := SearchIndex(idx, phrase)
+				if err != nil {
+					t.Errorf("[%s/%d] SearchIndex %q: error: %v", cfg.Name, workers, phrase, err)
+					continue
+				}
+				expected := fmt.Sprintf("%v", offsets)
+				actual := fmt.Sprintf("%v", results[i])
+				if expected != actual {
+					t.Errorf("[%s/%d] SearchAllParallel %q: expected %s, got %s", cfg.Name, workers, phrase, expected, actual)
+				}
+			}
+		}
+
+		if results, err := SearchAll(idx, nil); err != nil || len(results) != 0 {
+			t.Errorf("[%s] SearchAll: expected no results, got %v, %v", cfg.Name, results, err)
+		}
+
+		if err := idx.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
+
+// benchmarkSearchAllSetup indexes many copies of the sample text, and returns
+// the distinct words of the sample text as the phrases to search for, either
+// as they are or with an extra letter which makes most of them absent.
+func benchmarkSearchAllSetup(b *testing.B, suffix string) (*Index, []string) {
+	idx, err := BuildIndex(NewTextFromString(strings.Repeat(sampleText, 50)))
+	if err != nil {
+		b.Fatalf("BuildIndex: error: %v", err)
+	}
+	var phrases []string
+	seen := make(map[string]bool)
+	for _, word := range regexp.MustCompile(`[\pL\pN]+`).FindAllString(sampleText, -1) {
+		if !seen[word] {
+			seen[word] = true
+			phrases = append(phrases, word+suffix)
+		}
+	}
+	return idx, phrases
+}
+
+func BenchmarkSearchAll(b *testing.B) {
+	for _, suffix := range []string{"", "x"} {
+		b.Run(fmt.Sprintf("suffix=%q", suffix), func(b *testing.B) {
+			idx, phrases := benchmarkSearchAllSetup(b, suffix)
+			defer idx.Close()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := SearchAll(idx, phrases); err != nil {
+					b.Fatalf("SearchAll: error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSearchAll_SearchIndexLoop(b *testing.B) {
+	for _, suffix := range []string{"", "x"} {
+		b.Run(fmt.Sprintf("suffix=%q", suffix), func(b *testing.B) {
+			idx, phrases := benchmarkSearchAllSetup(b, suffix)
+			defer idx.Close()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, phrase := range phrases {
+					if _, err := SearchIndex(idx, phrase); err != nil {
+						b.Fatalf("SearchIndex: error: %v", err)
+					}
+				}
+			}
+		})
+	}
+}
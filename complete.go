@@ -0,0 +1,140 @@
+package suffixarray
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Completion is a continuation of a prefix, as returned by Complete.
+type Completion struct {
+	// Continuation is the text which follows the prefix, not including
+	// the prefix itself.
+	Continuation string
+
+	// Count is the number of times that the prefix is followed by the
+	// continuation in the text.
+	Count uint64
+}
+
+// SymbolCompletion is like Completion, but the continuation is given as a
+// list of symbols, as returned by CompleteSymbols.
+type SymbolCompletion struct {
+	Continuation []uint64
+	Count        uint64
+}
+
+// Complete returns the k most frequent continuations of the given prefix in
+// the indexed text, each up to maxLen symbols long, ranked by descending count
+// and then in lexicographic order.  The text must be a text of bytes; for
+// larger alphabets, use CompleteSymbols.
+//
+// A continuation is the maxLen symbols which follow an occurrence of the
+// prefix, or fewer if the text ends sooner.  The end of the text is not
+// counted as a continuation.
+//
+// On an Index built with FoldCase, the prefix matches without regard to case,
+// and each continuation is returned folded to lower case, with a count which
+// includes all of its case variants.
+//
+// The occurrences of the prefix form one range of the suffix array, found
+// with Range.  Within that range, suffixes sharing a continuation are
+// adjacent, and a new continuation begins exactly where the LCP array drops
+// below len(prefix) + maxLen.  So a single pass over the range of the LCP
+// array finds every distinct continuation along with its count, and only one
+// suffix of each is ever read from the text.
+//
+func Complete(idx *Index, prefix string, k int, maxLen uint64) ([]Completion, error) {
+	if idx.text.AlphabetSize() > 256 {
+		return nil, fmt.Errorf("Complete: alphabet size %d exceeds 256", idx.text.AlphabetSize())
+	}
+	found, err := CompleteSymbols(idx, stringToSymbols(prefix), k, maxLen)
+	if err != nil {
+		return nil, err
+	}
+
+	completions := make([]Completion, len(found))
+	for i, c := range found {
+		buf := make([]byte, len(c.Continuation))
+		for j, symbol := range c.Continuation {
+			buf[j] = byte(symbol)
+		}
+		completions[i] = Completion{string(buf), c.Count}
+	}
+	return completions, nil
+}
+
+// CompleteSymbols is like Complete, but the prefix and continuations are
+// given as lists of symbols.  This allows completing texts whose alphabet is
+// larger than 256.
+func CompleteSymbols(idx *Index, prefix []uint64, k int, maxLen uint64) ([]SymbolCompletion, error) {
+	text := idx.searchText()
+	phrase := idx.prepare(prefix)
+	m := uint64(len(phrase))
+	if maxLen > text.Len() {
+		maxLen = text.Len()
+	}
+
+	lo, hi, err := rangeSymbols(text, idx.sa, idx.lcplr, phrase)
+	if err != nil || lo == hi || k <= 0 || maxLen == 0 {
+		return nil, err
+	}
+
+	var completions []SymbolCompletion
+	emit := func(pos uint64, count uint64) error {
+		length := text.Len() - pos - m
+		if length > maxLen {
+			length = maxLen
+		}
+		if length == 0 {
+			return nil
+		}
+
+		buf := make([]uint64, 0, length)
+		iter := text.Iterate(pos+m, pos+m+length)
+		for iter.Next() {
+			buf = append(buf, iter.Symbol())
+		}
+		if err := iter.Close(); err != nil {
+			return err
+		}
+		completions = append(completions, SymbolCompletion{buf, count})
+		return nil
+	}
+
+	saIter := idx.sa.Iterate(lo, hi)
+	lcpIter := idx.lcp.Iterate(lo, hi)
+	var groupPos, groupSize uint64
+	for saIter.Next() && lcpIter.Next() {
+		if groupSize > 0 && lcpIter.Height() < m+maxLen {
+			if err = emit(groupPos, groupSize); err != nil {
+				break
+			}
+			groupSize = 0
+		}
+		if groupSize == 0 {
+			groupPos = saIter.Position()
+		}
+		groupSize++
+	}
+	if err == nil && groupSize > 0 {
+		err = emit(groupPos, groupSize)
+	}
+	if err2 := saIter.Close(); err == nil {
+		err = err2
+	}
+	if err2 := lcpIter.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The completions are already in lexicographic order.
+	sort.SliceStable(completions, func(i, j int) bool {
+		return completions[i].Count > completions[j].Count
+	})
+	if len(completions) > k {
+		completions = completions[:k]
+	}
+	return completions, nil
+}
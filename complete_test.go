@@ -0,0 +1,141 @@
+package suffixarray
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func NaiveComplete(text, prefix string, k int, maxLen int) []Completion {
+	counts := make(map[string]uint64)
+	for _, pos := range NaiveSearch(text, prefix) {
+		start := int(pos) + len(prefix)
+		end := start + maxLen
+		if end > len(text) {
+			end = len(text)
+		}
+		if start < end {
+			counts[text[start:end]]++
+		}
+	}
+
+	var out []Completion
+	for str, count := range counts {
+		out = append(out, Completion{str, count})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Continuation < out[j].Continuation
+	})
+	if len(out) > k {
+		out = out[:k]
+	}
+	return out
+}
+
+func TestComplete(t *testing.T) {
+	type testrow struct {
+		Prefix string
+		K      int
+		MaxLen int
+	}
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		idx, err := BuildIndex(NewTextFromString(sampleText, opts...), opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildIndex: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for i, row := range []testrow{
+			testrow{"dolor ", 5, 4},
+			testrow{"s", 10, 1},
+			testrow{"s", 3, 3},
+			testrow{"sit amet, ", 100, 20},
+			testrow{"Phasellus ", 2, 1000},
+			testrow{"", 5, 2},
+			testrow{"zzz", 5, 2},
+			testrow{"odio", 0, 5},
+		} {
+			completions, err := Complete(idx, row.Prefix, row.K, uint64(row.MaxLen))
+			if err != nil {
+				t.Errorf("[%s/%03d] Complete %q: error: %v", cfg.Name, i, row.Prefix, err)
+				continue
+			}
+
+			expected := fmt.Sprintf("%q", NaiveComplete(sampleText, row.Prefix, row.K, row.MaxLen))
+			actual := fmt.Sprintf("%q", completions)
+			if expected != actual {
+				t.Errorf("[%s/%03d] Complete %q %d %d: expected %s, got %s", cfg.Name, i, row.Prefix, row.K, row.MaxLen, expected, actual)
+			}
+		}
+
+		if err := idx.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
+
+func TestCompleteSymbols(t *testing.T) {
+	const scale = 300
+	scaled := func(str string) []uint64 {
+		out := make([]uint64, len(str))
+		for i := 0; i < len(str); i++ {
+			out[i] = uint64(str[i]) * scale
+		}
+		return out
+	}
+
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		text, err := NewText(256*scale, extendOptions(opts, NumValues(uint64(len(sampleText))))...)
+		if err != nil {
+			t.Errorf("[%s] NewText: error: %v", cfg.Name, err)
+			continue
+		}
+		iter := text.Iterate(0, text.Len())
+		for iter.Next() {
+			iter.SetSymbol(uint64(sampleText[iter.Index()]) * scale)
+		}
+		if err := iter.Close(); err != nil {
+			t.Errorf("[%s] Iterate: error: %v", cfg.Name, err)
+			text.Close()
+			continue
+		}
+
+		idx, err := BuildIndex(text, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildIndex: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for _, prefix := range []string{"dolor ", "Phasellus ", "zzz"} {
+			completions, err := CompleteSymbols(idx, scaled(prefix), 5, 4)
+			if err != nil {
+				t.Errorf("[%s] CompleteSymbols %q: error: %v", cfg.Name, prefix, err)
+				continue
+			}
+			var expected []SymbolCompletion
+			for _, c := range NaiveComplete(sampleText, prefix, 5, 4) {
+				expected = append(expected, SymbolCompletion{scaled(c.Continuation), c.Count})
+			}
+			if expected, actual := fmt.Sprintf("%v", expected), fmt.Sprintf("%v", completions); expected != actual {
+				t.Errorf("[%s] CompleteSymbols %q: expected %s, got %s", cfg.Name, prefix, expected, actual)
+			}
+		}
+
+		if _, err := Complete(idx, "dolor ", 5, 4); err == nil {
+			t.Errorf("[%s] Complete: expected error for alphabet size %d", cfg.Name, 256*scale)
+		}
+
+		if err := idx.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package suffixarray
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultSampleRate is the sample rate used by BuildCompressedSuffixArray
+// when no SampleRate option is given.
+const DefaultSampleRate = 32
+
+// psiBlockSize is the number of Ψ values encoded relative to each absolute
+// value in a CompressedSuffixArray.
+const psiBlockSize = 32
+
+var errReadOnlySuffixArray = errors.New("CompressedSuffixArray is read-only")
+
+// SuffixArrayReader is the read-only view of a suffix array which Search and
+// its relatives need.  Both SuffixArray and CompressedSuffixArray satisfy it.
+type SuffixArrayReader interface {
+	// Len returns the length of the array, which is always one greater
+	// than the length of the text.
+	Len() uint64
+
+	// PositionAt returns the text offset of the suffix at the given
+	// index.
+	PositionAt(index uint64) (uint64, error)
+
+	// Iterate constructs an Iterator in the forward direction.
+	Iterate(i, j uint64) *Iterator
+}
+
+// CompressedSuffixArray is a read-only suffix array which stores far less
+// than one full offset per suffix.
+//
+// Rather than the offsets themselves, it stores the function Ψ, which maps
+// the index of the suffix at offset N to the index of the suffix at offset
+// N+1.  Ψ is increasing across all the suffixes which begin with the same
+// symbol, so its successive differences are small, and are stored as varints
+// with an absolute value every psiBlockSize entries.  The offsets which are
+// multiples of the sample rate s are stored in full, together with a bit for
+// each index saying whether its offset was sampled.
+//
+// PositionAt follows Ψ from the given index until it reaches a sampled
+// offset, at most s-1 steps away, and subtracts the number of steps taken.
+// Each step decodes Ψ from the start of its block, which reads up to
+// psiBlockSize-1 varints, so at the default sample rate of 32 a single call
+// may read around a thousand bytes, and about a quarter of that on average.
+// A search which collects many matches pays this for each of them.  Larger
+// sample rates make the array smaller and PositionAt slower: the samples take
+// about n/s full offsets, while the expected number of steps is (s-1)/2.
+//
+// The array is built from a SuffixArray, which may be closed afterward.  It
+// only calls ValueAt on its arrays, so it is safe for concurrent use.
+//
+type CompressedSuffixArray struct {
+	n          uint64
+	sampleRate uint64
+	psi        Storage
+	psiBlocks  Storage
+	sampled    rankedBits
+	samples    Storage
+}
+
+// BuildCompressedSuffixArray constructs a CompressedSuffixArray with the same
+// contents as sa.  The SampleRate option sets how often offsets are sampled.
+func BuildCompressedSuffixArray(sa *SuffixArray, opts ...Option) (*CompressedSuffixArray, error) {
+	n := sa.Len()
+	if n == 0 {
+		return nil, errors.New("BuildCompressedSuffixArray: empty suffix array")
+	}
+
+	rate := makeBuildOptions(opts).sampleRate
+	if rate == 0 {
+		rate = DefaultSampleRate
+	}
+
+	// Ψ[i] is the rank of the suffix following SA[i], so it needs the
+	// inverse of the suffix array.
+	rank, err := makeStorage(extendOptions(
+		opts,
+		NumValues(n),
+		MaxValue(n),
+		WithFile(nil)))
+	if err != nil {
+		return nil, err
+	}
+	defer rank.Close()
+
+	err = sa.ForEach(func(index uint64, pos uint64) error {
+		return rank.SetValueAt(pos, index)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	csa := &CompressedSuffixArray{n: n, sampleRate: rate}
+	needClose := true
+	defer func() {
+		if needClose {
+			csa.Close()
+		}
+	}()
+
+	csa.psiBlocks, err = makeStorage(extendOptions(
+		opts,
+		NumValues((n+psiBlockSize-1)/psiBlockSize),
+		MaxValue(n*binary.MaxVarintLen64),
+		WithFile(nil)))
+	if err != nil {
+		return nil, err
+	}
+
+	// The empty suffix at offset n-1 is always sampled, so that following
+	// Ψ never wraps around to offset 0.
+	numSamples := (n-1+rate-1)/rate + 1
+	csa.samples, err = makeStorage(extendOptions(
+		opts,
+		NumValues(numSamples),
+		MaxValue(n),
+		WithFile(nil)))
+	if err != nil {
+		return nil, err
+	}
+
+	psi, err := newArrayBuilder(extendOptions(opts, MaxValue(255), WithFile(nil)))
+	if err != nil {
+		return nil, err
+	}
+	defer psi.Close()
+
+	sampled, err := newRankedBitsWriter(n, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf [binary.MaxVarintLen64]byte
+	var prev, numSampled uint64
+	blockIter := csa.psiBlocks.Iterate(0, csa.psiBlocks.Len())
+	sampleIter := csa.samples.Iterate(0, csa.samples.Len())
+	err = sa.ForEach(func(index uint64, pos uint64) error {
+		next, err := rank.ValueAt((pos + 1) % n)
+		if err != nil {
+			return err
+		}
+
+		var size int
+		if index%psiBlockSize == 0 {
+			if !blockIter.Next() {
+				return blockIter.Err()
+			}
+			blockIter.SetValue(psi.Len())
+			size = binary.PutUvarint(buf[:], next)
+		} else {
+			size = binary.PutVarint(buf[:], int64(next-prev))
+		}
+		for _, b := range buf[:size] {
+			if err := psi.Append(uint64(b)); err != nil {
+				return err
+			}
+		}
+		prev = next
+
+		isSample := pos%rate == 0 || pos == n-1
+		sampled.Append(isSample)
+		if isSample {
+			if !sampleIter.Next() {
+				return sampleIter.Err()
+			}
+			sampleIter.SetValue(pos)
+			numSampled++
+		}
+		return nil
+	})
+	if err2 := blockIter.Close(); err == nil {
+		err = err2
+	}
+	if err2 := sampleIter.Close(); err == nil {
+		err = err2
+	}
+	rb, _, err2 := sampled.Finish()
+	csa.sampled = rb
+	if err == nil {
+		err = err2
+	}
+	if err != nil {
+		return nil, err
+	}
+	if numSampled != numSamples {
+		return nil, fmt.Errorf("BuildCompressedSuffixArray: expected %d samples, found %d", numSamples, numSampled)
+	}
+
+	csa.psi, err = psi.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	needClose = false
+	return csa, nil
+}
+
+// SampleRate returns the spacing of the offsets which are stored in full.
+func (csa *CompressedSuffixArray) SampleRate() uint64 { return csa.sampleRate }
+
+// Len returns the length of the array, which is always one greater than the
+// length of the text.
+func (csa *CompressedSuffixArray) Len() uint64 { return csa.n }
+
+// PositionAt returns the text offset of the suffix at the given index.  It
+// takes up to SampleRate()-1 steps of Ψ; see CompressedSuffixArray.
+func (csa *CompressedSuffixArray) PositionAt(index uint64) (uint64, error) {
+	pos, _, err := csa.positionAt(index)
+	return pos, err
+}
+
+// positionAt is like PositionAt, but also returns the number of steps of Ψ
+// which it took.
+func (csa *CompressedSuffixArray) positionAt(index uint64) (uint64, uint64, error) {
+	if index >= csa.n {
+		return 0, 0, io.EOF
+	}
+	var steps uint64
+	for {
+		isSample, err := csa.sampled.bitAt(index)
+		if err != nil {
+			return 0, 0, err
+		}
+		if isSample {
+			j, err := csa.sampled.rank1(index)
+			if err != nil {
+				return 0, 0, err
+			}
+			pos, err := csa.samples.ValueAt(j)
+			return pos - steps, steps, err
+		}
+		index, err = csa.psiAt(index)
+		if err != nil {
+			return 0, 0, err
+		}
+		steps++
+	}
+}
+
+// psiAt returns Ψ[index], the index of the suffix which follows SA[index] in
+// the text.
+func (csa *CompressedSuffixArray) psiAt(index uint64) (uint64, error) {
+	block := index / psiBlockSize
+	start, err := csa.psiBlocks.ValueAt(block)
+	if err != nil {
+		return 0, err
+	}
+
+	r := &storageByteReader{ba: csa.psi, index: start}
+	value, err := binary.ReadUvarint(r)
+	for i := block * psiBlockSize; err == nil && i < index; i++ {
+		var delta int64
+		delta, err = binary.ReadVarint(r)
+		value += uint64(delta)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("CompressedSuffixArray: decoding Ψ[%d]: %v", index, err)
+	}
+	return value, nil
+}
+
+// Iterate constructs an Iterator in the forward direction.  The Iterator is
+// read-only: SetPosition records an error.
+func (csa *CompressedSuffixArray) Iterate(i, j uint64) *Iterator {
+	if i > j || j > csa.n {
+		panic(fmt.Errorf("CompressedSuffixArray.Iterate: invalid range [%d, %d) for length %d", i, j, csa.n))
+	}
+	return &Iterator{impl: &csaIterator{csa: csa, next: i, end: j, step: 1}}
+}
+
+// ReverseIterate constructs an Iterator in the reverse direction.
+func (csa *CompressedSuffixArray) ReverseIterate(i, j uint64) *Iterator {
+	if i > j || j > csa.n {
+		panic(fmt.Errorf("CompressedSuffixArray.ReverseIterate: invalid range [%d, %d) for length %d", i, j, csa.n))
+	}
+	return &Iterator{impl: &csaIterator{csa: csa, next: j - 1, end: i - 1, step: ^uint64(0)}}
+}
+
+// ForEach is a convenience method that forward-iterates over the entire array.
+func (csa *CompressedSuffixArray) ForEach(fn func(uint64, uint64) error) error {
+	iter := csa.Iterate(0, csa.n)
+	for iter.Next() {
+		if err := fn(iter.Index(), iter.Position()); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	return iter.Close()
+}
+
+// Close frees the resources used by the array.
+func (csa *CompressedSuffixArray) Close() error {
+	var finalError error
+	for _, ba := range []Storage{csa.psi, csa.psiBlocks, csa.samples} {
+		if ba == nil {
+			continue
+		}
+		if err := ba.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	if csa.sampled.words != nil {
+		if err := csa.sampled.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	csa.psi, csa.psiBlocks, csa.samples = nil, nil, nil
+	csa.sampled = rankedBits{}
+	return finalError
+}
+
+// storageByteReader reads the bytes stored in a Storage, one per value.
+type storageByteReader struct {
+	ba    Storage
+	index uint64
+}
+
+func (r *storageByteReader) ReadByte() (byte, error) {
+	value, err := r.ba.ValueAt(r.index)
+	if err != nil {
+		return 0, err
+	}
+	r.index++
+	return byte(value), nil
+}
+
+// csaIterator walks a CompressedSuffixArray, computing each position as it
+// goes.  As with sliceIterator, indices wrap around in the reverse direction.
+type csaIterator struct {
+	csa   *CompressedSuffixArray
+	index uint64
+	value uint64
+	next  uint64
+	end   uint64
+	step  uint64
+	err   error
+}
+
+func (iter *csaIterator) Next() bool { return iter.Skip(1) }
+
+func (iter *csaIterator) Skip(n uint64) bool {
+	if iter.err != nil {
+		return false
+	}
+	remaining := iter.end - iter.next
+	if iter.step != 1 {
+		remaining = iter.next - iter.end
+	}
+	if n > remaining {
+		iter.next = iter.end
+		return false
+	}
+	if n > 0 {
+		iter.index = iter.next + (n-1)*iter.step
+		iter.next = iter.index + iter.step
+		iter.value, iter.err = iter.csa.PositionAt(iter.index)
+	}
+	return iter.err == nil
+}
+
+func (iter *csaIterator) Index() uint64 { return iter.index }
+
+func (iter *csaIterator) Value() uint64 { return iter.value }
+
+func (iter *csaIterator) SetValue(value uint64) {
+	if iter.err == nil {
+		iter.err = errReadOnlySuffixArray
+	}
+}
+
+func (iter *csaIterator) Err() error { return iter.err }
+
+func (iter *csaIterator) Flush() error { return iter.err }
+
+func (iter *csaIterator) Close() error { return iter.err }
+
+var _ SuffixArrayReader = (*SuffixArray)(nil)
+var _ SuffixArrayReader = (*CompressedSuffixArray)(nil)
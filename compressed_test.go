@@ -0,0 +1,174 @@
+package suffixarray
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCompressedSuffixArray(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		text := NewTextFromString(sampleText, opts...)
+		sa, err := BuildSuffixArray(text, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildSuffixArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		var expected []uint64
+		if err := sa.ForEach(func(index, pos uint64) error {
+			expected = append(expected, pos)
+			return nil
+		}); err != nil {
+			t.Errorf("[%s] ForEach: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for _, rate := range []uint64{0, 1, 5, 64} {
+			csa, err := BuildCompressedSuffixArray(sa, extendOptions(opts, SampleRate(rate))...)
+			if err != nil {
+				t.Errorf("[%s/%d] BuildCompressedSuffixArray: error: %v", cfg.Name, rate, err)
+				continue
+			}
+			if csa.Len() != sa.Len() {
+				t.Errorf("[%s/%d] Len: expected %d, got %d", cfg.Name, rate, sa.Len(), csa.Len())
+			}
+
+			for index, pos := range expected {
+				actual, err := csa.PositionAt(uint64(index))
+				if err != nil || actual != pos {
+					t.Errorf("[%s/%d] PositionAt %d: expected %d, got %d, %v", cfg.Name, rate, index, pos, actual, err)
+					break
+				}
+			}
+
+			var reversed []uint64
+			iter := csa.ReverseIterate(100, 200)
+			for iter.Next() {
+				reversed = append(reversed, iter.Position())
+			}
+			if err := iter.Close(); err != nil {
+				t.Errorf("[%s/%d] ReverseIterate: error: %v", cfg.Name, rate, err)
+			}
+			for i, pos := range reversed {
+				if pos != expected[199-i] {
+					t.Errorf("[%s/%d] ReverseIterate: index %d: expected %d, got %d", cfg.Name, rate, 199-i, expected[199-i], pos)
+					break
+				}
+			}
+
+			iter = csa.Iterate(0, 1)
+			iter.Next()
+			iter.SetPosition(0)
+			if err := iter.Close(); err == nil {
+				t.Errorf("[%s/%d] SetPosition: expected error", cfg.Name, rate)
+			}
+
+			for _, phrase := range []string{searchPhrase, "Lorem", "a", "ips ", "zzz", "\n"} {
+				expected := fmt.Sprintf("%v", NaiveSearch(sampleText, phrase))
+				offsets, err := Search(text, csa, nil, phrase)
+				if err != nil {
+					t.Errorf("[%s/%d] Search %q: error: %v", cfg.Name, rate, phrase, err)
+					continue
+				}
+				if actual := fmt.Sprintf("%v", offsets); expected != actual {
+					t.Errorf("[%s/%d] Search %q: expected %s, got %s", cfg.Name, rate, phrase, expected, actual)
+				}
+			}
+
+			if err := csa.Close(); err != nil {
+				t.Errorf("[%s/%d] Close: error: %v", cfg.Name, rate, err)
+			}
+		}
+
+		if err := sa.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
+
+func TestCompressedSuffixArray_Empty(t *testing.T) {
+	text := NewTextFromString("")
+	sa, err := BuildSuffixArray(text)
+	if err != nil {
+		t.Fatalf("BuildSuffixArray: error: %v", err)
+	}
+	csa, err := BuildCompressedSuffixArray(sa)
+	if err != nil {
+		t.Fatalf("BuildCompressedSuffixArray: error: %v", err)
+	}
+	if pos, err := csa.PositionAt(0); err != nil || pos != 0 {
+		t.Errorf("PositionAt 0: expected 0, got %d, %v", pos, err)
+	}
+	if offsets, err := Search(text, csa, nil, "a"); err != nil || len(offsets) != 0 {
+		t.Errorf("Search: expected no matches, got %v, %v", offsets, err)
+	}
+	if err := csa.Close(); err != nil {
+		t.Errorf("Close: error: %v", err)
+	}
+}
+
+func TestCompressedSuffixArray_SampleRate(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		sa, err := BuildSuffixArray(NewTextFromString(sampleText, opts...), opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildSuffixArray: error: %v", cfg.Name, err)
+			continue
+		}
+		n := sa.Len()
+
+		// Each doubling of the rate should roughly halve the samples
+		// and double the steps which PositionAt takes.
+		var prevSamples uint64
+		var prevMean float64
+		for i, rate := range []uint64{1, 2, 8, 32} {
+			csa, err := BuildCompressedSuffixArray(sa, extendOptions(opts, SampleRate(rate))...)
+			if err != nil {
+				t.Errorf("[%s/%d] BuildCompressedSuffixArray: error: %v", cfg.Name, rate, err)
+				continue
+			}
+
+			samples := csa.samples.Len()
+			if samples > n/rate+2 {
+				t.Errorf("[%s/%d] samples: expected at most %d, got %d", cfg.Name, rate, n/rate+2, samples)
+			}
+
+			var total, max uint64
+			for index := uint64(0); index < n; index++ {
+				_, steps, err := csa.positionAt(index)
+				if err != nil {
+					t.Errorf("[%s/%d] positionAt %d: error: %v", cfg.Name, rate, index, err)
+					break
+				}
+				total += steps
+				if steps > max {
+					max = steps
+				}
+			}
+			mean := float64(total) / float64(n)
+			if max > rate-1 {
+				t.Errorf("[%s/%d] steps: expected at most %d, got %d", cfg.Name, rate, rate-1, max)
+			}
+			if expected := float64(rate-1) / 2; mean < expected*0.8 || mean > expected*1.2 {
+				t.Errorf("[%s/%d] steps: expected a mean near %.1f, got %.1f", cfg.Name, rate, expected, mean)
+			}
+			if i > 0 && (samples >= prevSamples || mean <= prevMean) {
+				t.Errorf("[%s/%d] expected fewer samples and more steps than the previous rate, got %d vs %d and %.1f vs %.1f", cfg.Name, rate, samples, prevSamples, mean, prevMean)
+			}
+			prevSamples, prevMean = samples, mean
+
+			if err := csa.Close(); err != nil {
+				t.Errorf("[%s/%d] Close: error: %v", cfg.Name, rate, err)
+			}
+		}
+
+		if err := sa.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
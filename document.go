@@ -0,0 +1,346 @@
+package suffixarray
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DocumentIndex is an Index over a collection of documents, concatenated
+// into one Text with DocumentSeparator between each pair, such as the Text
+// returned by MergeSuffixArrays.  Documents are numbered from 0 in order of
+// their position in the Text.
+//
+// In addition to the Index, it keeps the document array, which gives the
+// document containing the suffix at each index of the suffix array, and for
+// each index, the previous index in the suffix array whose suffix belongs to
+// the same document.  A range minimum query over the latter finds the first
+// occurrence of each distinct document within a range of the suffix array,
+// as described by Muthukrishnan in [1].
+//
+//  [1] S. Muthukrishnan, “Efficient algorithms for document retrieval
+//      problems”, SODA 2002.
+//
+type DocumentIndex struct {
+	idx    *Index
+	starts Storage
+	da     Storage
+	prev   Storage
+	rmq    *rangeMinimum
+}
+
+// DocumentCount is the number of occurrences of a phrase in one document, as
+// returned by TopKDocuments.
+type DocumentCount struct {
+	Document uint64
+	Count    uint64
+}
+
+// BuildDocumentIndex constructs a DocumentIndex on top of an Index of a
+// separated collection of documents.  The DocumentIndex takes ownership of
+// the Index: closing the DocumentIndex also closes the Index.
+func BuildDocumentIndex(idx *Index, opts ...Option) (*DocumentIndex, error) {
+	di := &DocumentIndex{idx: idx}
+
+	needClose := true
+	defer func() {
+		if needClose {
+			di.closeArrays()
+		}
+	}()
+
+	n := idx.sa.Len()
+	text := idx.searchText()
+
+	maxValue := text.Len()
+	if maxValue == 0 {
+		maxValue = 1
+	}
+	starts, err := newArrayBuilder(extendOptions(
+		opts,
+		MaxValue(maxValue)))
+	if err != nil {
+		return nil, err
+	}
+	defer starts.Close()
+
+	if err := starts.Append(0); err != nil {
+		return nil, err
+	}
+	err = text.ForEach(func(index uint64, symbol uint64) error {
+		if symbol == DocumentSeparator {
+			return starts.Append(index + 1)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if di.starts, err = starts.Finish(); err != nil {
+		return nil, err
+	}
+	numDocs := di.starts.Len()
+
+	di.da, err = makeStorage(extendOptions(
+		opts,
+		NumValues(n),
+		MaxValue(numDocs)))
+	if err != nil {
+		return nil, err
+	}
+	di.prev, err = makeStorage(extendOptions(
+		opts,
+		NumValues(n),
+		MaxValue(n)))
+	if err != nil {
+		return nil, err
+	}
+
+	// last holds, for each document, one more than the index of the last
+	// suffix seen from that document, or 0 if there is none yet.
+	last, err := makeStorage(extendOptions(
+		opts,
+		NumValues(numDocs),
+		MaxValue(n),
+		WithFile(nil)))
+	if err != nil {
+		return nil, err
+	}
+	defer last.Close()
+
+	daIter := di.da.Iterate(0, n)
+	prevIter := di.prev.Iterate(0, n)
+	err = idx.sa.ForEach(func(index uint64, pos uint64) error {
+		doc, err := di.DocumentAt(pos)
+		if err != nil {
+			return err
+		}
+		p, err := last.ValueAt(doc)
+		if err != nil {
+			return err
+		}
+		if err := last.SetValueAt(doc, index+1); err != nil {
+			return err
+		}
+		if daIter.Next() && prevIter.Next() {
+			daIter.SetValue(doc)
+			prevIter.SetValue(p)
+		}
+		return nil
+	})
+	if err2 := daIter.Close(); err == nil {
+		err = err2
+	}
+	if err2 := prevIter.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if di.rmq, err = buildRangeMinimum(di.prev, opts); err != nil {
+		return nil, err
+	}
+
+	needClose = false
+	return di, nil
+}
+
+// Index returns the underlying Index.
+func (di *DocumentIndex) Index() *Index { return di.idx }
+
+// NumDocuments returns the number of documents in the collection, which is
+// one more than the number of separators.
+func (di *DocumentIndex) NumDocuments() uint64 { return di.starts.Len() }
+
+// DocumentStart returns the text offset at which the given document begins.
+func (di *DocumentIndex) DocumentStart(doc uint64) (uint64, error) {
+	if doc >= di.NumDocuments() {
+		return 0, fmt.Errorf("DocumentIndex.DocumentStart: document %d is out of range [0, %d)", doc, di.NumDocuments())
+	}
+	return di.starts.ValueAt(doc)
+}
+
+// DocumentEnd returns the text offset just past the end of the given
+// document, not counting the separator which follows it.
+func (di *DocumentIndex) DocumentEnd(doc uint64) (uint64, error) {
+	if doc >= di.NumDocuments() {
+		return 0, fmt.Errorf("DocumentIndex.DocumentEnd: document %d is out of range [0, %d)", doc, di.NumDocuments())
+	}
+	if doc == di.NumDocuments()-1 {
+		return di.idx.Len(), nil
+	}
+	next, err := di.starts.ValueAt(doc + 1)
+	if err != nil {
+		return 0, err
+	}
+	return next - 1, nil
+}
+
+// DocumentAt returns the document containing the given text offset.  A
+// separator belongs to the document which it ends, and the end of the text
+// belongs to the last document.
+func (di *DocumentIndex) DocumentAt(offset uint64) (uint64, error) {
+	if offset > di.idx.Len() {
+		return 0, fmt.Errorf("DocumentIndex.DocumentAt: offset %d is out of range for text of length %d", offset, di.idx.Len())
+	}
+	next, err := searchIndices(0, di.starts.Len(), func(index uint64) (bool, error) {
+		start, err := di.starts.ValueAt(index)
+		return start > offset, err
+	})
+	return next - 1, err
+}
+
+// Close frees the resources used by the DocumentIndex, including its Index.
+func (di *DocumentIndex) Close() error {
+	err := di.closeArrays()
+	if err2 := di.idx.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (di *DocumentIndex) closeArrays() error {
+	var finalError error
+	if di.rmq != nil {
+		if err := di.rmq.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	if di.prev != nil {
+		if err := di.prev.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	if di.da != nil {
+		if err := di.da.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	if di.starts != nil {
+		if err := di.starts.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	return finalError
+}
+
+// DocumentListing returns, in ascending order, the distinct documents in
+// which phrase occurs.
+//
+// Within the suffix array range of the phrase, the first occurrence of each
+// document is exactly an index whose previous occurrence of the same
+// document lies before the range.  The index with the smallest previous
+// occurrence is found by a range minimum query; if it lies before the range,
+// its document is reported and the two halves of the range on either side
+// are searched in turn.  Each query either reports a new document or ends a
+// branch, so the cost is proportional to the number of documents reported
+// rather than the number of occurrences.
+//
+func DocumentListing(di *DocumentIndex, phrase string) ([]uint64, error) {
+	return documentListing(di, stringToSymbols(phrase))
+}
+
+// documentListing is like DocumentListing, but the phrase is given as a list
+// of symbols.
+func documentListing(di *DocumentIndex, phrase []uint64) ([]uint64, error) {
+	lo, hi, err := rangeSymbols(di.idx.searchText(), di.idx.sa, di.idx.lcplr, di.idx.prepare(phrase))
+	if err != nil || lo == hi {
+		return nil, err
+	}
+	return listDocuments(di, lo, hi)
+}
+
+// listDocuments returns, in ascending order, the distinct documents of the
+// suffixes in the suffix array range [lo, hi).
+func listDocuments(di *DocumentIndex, lo, hi uint64) ([]uint64, error) {
+	var docs []uint64
+	stack := []batchRange{{lo, hi}}
+	for len(stack) > 0 {
+		r := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if r.lo >= r.hi {
+			continue
+		}
+
+		i, err := di.rmq.ArgMin(r.lo, r.hi)
+		if err != nil {
+			return nil, err
+		}
+		p, err := di.prev.ValueAt(i)
+		if err != nil {
+			return nil, err
+		}
+		if p > lo {
+			// The previous occurrence, at index p-1, lies within the
+			// range, so every document here was already reported.
+			continue
+		}
+
+		doc, err := di.da.ValueAt(i)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+		stack = append(stack, batchRange{i + 1, r.hi}, batchRange{r.lo, i})
+	}
+
+	sort.Sort(byU64(docs))
+	return docs, nil
+}
+
+// TopKDocuments returns the k documents in which phrase occurs most often,
+// ranked by descending count and then by document number.
+//
+// The suffix array range of the phrase is found once.  The distinct documents
+// within it are found as by DocumentListing, and the occurrences within each
+// one are counted by the wavelet matrix of SearchInRange over the document's
+// span of the text, so the occurrences themselves are never enumerated and
+// the phrase is never searched for again.
+//
+func TopKDocuments(di *DocumentIndex, phrase string, k int) ([]DocumentCount, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	symbols := di.idx.prepare(stringToSymbols(phrase))
+	m := uint64(len(symbols))
+	lo, hi, err := rangeSymbols(di.idx.searchText(), di.idx.sa, di.idx.lcplr, symbols)
+	if err != nil || lo == hi {
+		return nil, err
+	}
+	docs, err := listDocuments(di, lo, hi)
+	if err != nil {
+		return nil, err
+	}
+	wm, err := di.idx.waveletMatrix()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]DocumentCount, 0, len(docs))
+	for _, doc := range docs {
+		start, err := di.DocumentStart(doc)
+		if err != nil {
+			return nil, err
+		}
+		end, err := di.DocumentEnd(doc)
+		if err != nil {
+			return nil, err
+		}
+		var count uint64
+		if end-start >= m {
+			count, err = wm.Count(lo, hi, start, end-m+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+		counts = append(counts, DocumentCount{doc, count})
+	}
+
+	sort.SliceStable(counts, func(i, j int) bool {
+		return counts[i].Count > counts[j].Count
+	})
+	if len(counts) > k {
+		counts = counts[:k]
+	}
+	return counts, nil
+}
@@ -0,0 +1,97 @@
+package suffixarray
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func NaiveTopKDocuments(docs []string, phrase string, k int) []DocumentCount {
+	var out []DocumentCount
+	for i, doc := range docs {
+		if count := len(NaiveSearch(doc, phrase)); count > 0 {
+			out = append(out, DocumentCount{uint64(i), uint64(count)})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Count > out[j].Count
+	})
+	if len(out) > k {
+		out = out[:k]
+	}
+	return out
+}
+
+func TestDocumentIndex(t *testing.T) {
+	docs := strings.SplitAfter(sampleText, ".")
+	docs = append(docs, "", "sit sit sit amet", "amet")
+	input := strings.Join(docs, "\x00")
+	phrases := []string{searchPhrase, "sit", "amet", "a", "Lorem", "zzz", "us. ", ".", ""}
+
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		idx, err := BuildIndex(NewTextFromString(input, opts...), opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildIndex: error: %v", cfg.Name, err)
+			continue
+		}
+		di, err := BuildDocumentIndex(idx, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildDocumentIndex: error: %v", cfg.Name, err)
+			idx.Close()
+			continue
+		}
+
+		if di.NumDocuments() != uint64(len(docs)) {
+			t.Errorf("[%s] NumDocuments: expected %d, got %d", cfg.Name, len(docs), di.NumDocuments())
+		}
+		offset := uint64(0)
+		for i, doc := range docs {
+			end := offset + uint64(len(doc))
+			if start, err := di.DocumentStart(uint64(i)); err != nil || start != offset {
+				t.Errorf("[%s] DocumentStart %d: expected %d, got %d, %v", cfg.Name, i, offset, start, err)
+			}
+			if actual, err := di.DocumentEnd(uint64(i)); err != nil || actual != end {
+				t.Errorf("[%s] DocumentEnd %d: expected %d, got %d, %v", cfg.Name, i, end, actual, err)
+			}
+			if actual, err := di.DocumentAt(end); err != nil || actual != uint64(i) {
+				t.Errorf("[%s] DocumentAt %d: expected %d, got %d, %v", cfg.Name, end, i, actual, err)
+			}
+			offset = end + 1
+		}
+
+		for _, phrase := range phrases {
+			var expectedDocs []uint64
+			for i, doc := range docs {
+				if strings.Contains(doc, phrase) {
+					expectedDocs = append(expectedDocs, uint64(i))
+				}
+			}
+			actualDocs, err := DocumentListing(di, phrase)
+			if err != nil {
+				t.Errorf("[%s] DocumentListing %q: error: %v", cfg.Name, phrase, err)
+			} else if expected, actual := fmt.Sprintf("%v", expectedDocs), fmt.Sprintf("%v", actualDocs); expected != actual {
+				t.Errorf("[%s] DocumentListing %q: expected %s, got %s", cfg.Name, phrase, expected, actual)
+			}
+
+			for _, k := range []int{1, 3, 100} {
+				counts, err := TopKDocuments(di, phrase, k)
+				if err != nil {
+					t.Errorf("[%s] TopKDocuments %q %d: error: %v", cfg.Name, phrase, k, err)
+					continue
+				}
+				expected := fmt.Sprintf("%v", NaiveTopKDocuments(docs, phrase, k))
+				if actual := fmt.Sprintf("%v", counts); expected != actual {
+					t.Errorf("[%s] TopKDocuments %q %d: expected %s, got %s", cfg.Name, phrase, k, expected, actual)
+				}
+			}
+		}
+
+		if err := di.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
@@ -0,0 +1,539 @@
+package suffixarray
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The symbols of a DNA Text, in sorted order.  Records are separated by
+// DocumentSeparator, which sorts before every base.
+const (
+	BaseA uint64 = 1
+	BaseC uint64 = 2
+	BaseG uint64 = 3
+	BaseN uint64 = 4
+	BaseT uint64 = 5
+
+	// DNAAlphabetSize is the AlphabetSize of a DNA Text.
+	DNAAlphabetSize uint64 = 6
+)
+
+// SequenceSet is a collection of named DNA sequences, such as the records of
+// a FASTA or FASTQ file, concatenated into one Text over the DNA alphabet.
+//
+// Each record is followed by DocumentSeparator, except the last, so an Index
+// over the Text can be wrapped in a DocumentIndex whose documents are the
+// records; BuildSequenceIndex does exactly that.  Line breaks and other
+// whitespace within a sequence are dropped, so offsets within a record count
+// bases.  The Text only needs 3 bits per base, so the PackedSymbols option is
+// worthwhile for large inputs.  The Text is accumulated in temporary storage
+// as the records are read, and becomes the Text without being copied, unless
+// a WithFile option asks for the Text to be kept in a file: since temporary
+// storage is replaced as it grows, the Text is then copied into the file once
+// all the records have been read.
+//
+type SequenceSet struct {
+	text   *Text
+	names  []string
+	starts Storage
+}
+
+// SequenceHit is an occurrence of a phrase in a SequenceSet, given as the
+// record and the offset of the first base within that record.
+type SequenceHit struct {
+	Record   uint64
+	Position uint64
+}
+
+// ReadFASTA reads FASTA records from r until EOF and constructs a
+// SequenceSet.
+//
+// Each record begins with a header line starting with '>', whose first word
+// is the record name; the rest of the header is ignored, as are lines
+// starting with ';'.  Bases may be upper or lower case.  U is read as T, and
+// the IUPAC ambiguity codes are read as N.
+//
+func ReadFASTA(r io.Reader, opts ...Option) (*SequenceSet, error) {
+	sr, err := newSequenceReader(r, "ReadFASTA", opts)
+	if err != nil {
+		return nil, err
+	}
+	defer sr.Close()
+
+	for {
+		ch, err := sr.br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch ch {
+		case '>':
+			header, err := sr.readLine()
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			if err := sr.beginRecord(header); err != nil {
+				return nil, err
+			}
+
+		case ';':
+			if _, err := sr.readLine(); err != nil && err != io.EOF {
+				return nil, err
+			}
+
+		case '\n':
+			sr.line++
+
+		case '\r':
+
+		default:
+			if len(sr.names) == 0 {
+				return nil, fmt.Errorf("ReadFASTA: line %d: sequence before the first '>' header", sr.line)
+			}
+			sr.br.UnreadByte()
+			if _, err := sr.readSequenceLine(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sr.Finish()
+}
+
+// ReadFASTQ reads FASTQ records from r until EOF and constructs a
+// SequenceSet.
+//
+// Each record is a header line starting with '@', whose first word is the
+// record name, then the sequence, a separator line starting with '+', and
+// as many quality scores as there are bases.  The sequence and the quality
+// scores may span several lines.  The quality scores are checked for length
+// and then discarded.  Bases are read as for ReadFASTA.
+//
+func ReadFASTQ(r io.Reader, opts ...Option) (*SequenceSet, error) {
+	sr, err := newSequenceReader(r, "ReadFASTQ", opts)
+	if err != nil {
+		return nil, err
+	}
+	defer sr.Close()
+
+	for {
+		ch, err := sr.br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ch == '\n' {
+			sr.line++
+			continue
+		}
+		if ch == '\r' {
+			continue
+		}
+		if ch != '@' {
+			return nil, fmt.Errorf("ReadFASTQ: line %d: expected '@' header, found %q", sr.line, ch)
+		}
+
+		header, err := sr.readLine()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if err := sr.beginRecord(header); err != nil {
+			return nil, err
+		}
+
+		var length uint64
+		for {
+			ch, err := sr.br.ReadByte()
+			if err == io.EOF {
+				return nil, fmt.Errorf("ReadFASTQ: line %d: missing '+' line for record %q", sr.line, sr.names[len(sr.names)-1])
+			}
+			if err != nil {
+				return nil, err
+			}
+			if ch == '+' {
+				if _, err := sr.readLine(); err != nil && err != io.EOF {
+					return nil, err
+				}
+				break
+			}
+			sr.br.UnreadByte()
+			n, err := sr.readSequenceLine()
+			if err != nil {
+				return nil, err
+			}
+			length += n
+		}
+
+		var quality uint64
+		for quality < length {
+			line, err := sr.readLine()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			quality += uint64(len(line))
+		}
+		if quality != length {
+			return nil, fmt.Errorf("ReadFASTQ: line %d: record %q has %d bases but %d quality scores", sr.line, sr.names[len(sr.names)-1], length, quality)
+		}
+	}
+	return sr.Finish()
+}
+
+// sequenceReader holds the state shared by ReadFASTA and ReadFASTQ.  The
+// symbols are appended directly to storage made as for a Text, which becomes
+// the Text of the SequenceSet, or is copied into the caller's file if the
+// options give one.
+type sequenceReader struct {
+	br      *bufio.Reader
+	fn      string
+	opts    []Option
+	line    uint64
+	symbols *arrayBuilder
+	starts  *arrayBuilder
+	names   []string
+}
+
+func newSequenceReader(r io.Reader, fn string, opts []Option) (*sequenceReader, error) {
+	symbols, err := newArrayBuilderWith(extendOptions(
+		opts,
+		MaxValue(DNAAlphabetSize-1),
+		WithFile(nil)), makeTextStorage)
+	if err != nil {
+		return nil, err
+	}
+	starts, err := newArrayBuilder(extendOptions(
+		opts,
+		BytesPerValue(8),
+		WithFile(nil)))
+	if err != nil {
+		symbols.Close()
+		return nil, err
+	}
+	return &sequenceReader{
+		br:      bufio.NewReader(r),
+		fn:      fn,
+		opts:    opts,
+		line:    1,
+		symbols: symbols,
+		starts:  starts,
+	}, nil
+}
+
+// readLine returns the rest of the current line, without its line ending.
+// It returns io.EOF only if there is nothing left to read.
+func (sr *sequenceReader) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := sr.br.ReadSlice('\n')
+		line = append(line, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && len(line) > 0 {
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+		sr.line++
+	}
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return line, nil
+}
+
+// readSequenceLine appends the bases on the rest of the current line and
+// returns how many there were.
+func (sr *sequenceReader) readSequenceLine() (uint64, error) {
+	var n uint64
+	for {
+		ch, err := sr.br.ReadByte()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		switch ch {
+		case '\n':
+			sr.line++
+			return n, nil
+		case ' ', '\t', '\r':
+			continue
+		}
+
+		symbol, ok := dnaBase(ch)
+		if !ok && strings.IndexByte("RYSWKMBDHVryswkmbdhv", ch) >= 0 {
+			symbol, ok = BaseN, true
+		}
+		if !ok {
+			return n, fmt.Errorf("%s: line %d: invalid base %q", sr.fn, sr.line, ch)
+		}
+		if err := sr.symbols.Append(symbol); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// beginRecord starts a new record with the given header line.
+func (sr *sequenceReader) beginRecord(header []byte) error {
+	if len(sr.names) > 0 {
+		if err := sr.symbols.Append(DocumentSeparator); err != nil {
+			return err
+		}
+	}
+	if err := sr.starts.Append(sr.symbols.Len()); err != nil {
+		return err
+	}
+	var name []byte
+	if fields := bytes.Fields(header); len(fields) > 0 {
+		name = fields[0]
+	}
+	sr.names = append(sr.names, string(name))
+	return nil
+}
+
+// Finish constructs the SequenceSet from the records read so far.
+func (sr *sequenceReader) Finish() (*SequenceSet, error) {
+	symbols, err := sr.symbols.Finish()
+	if err != nil {
+		return nil, err
+	}
+	text := &Text{ab: DNAAlphabetSize, ba: symbols}
+
+	if makeStorageOptions(sr.opts).file {
+		final, err := NewText(DNAAlphabetSize, extendOptions(sr.opts, NumValues(text.Len()))...)
+		if err != nil {
+			text.Close()
+			return nil, err
+		}
+		err = final.CopyFrom(text)
+		text.Close()
+		if err != nil {
+			final.Close()
+			return nil, err
+		}
+		text = final
+	}
+
+	starts, err := sr.starts.Finish()
+	if err != nil {
+		text.Close()
+		return nil, err
+	}
+
+	return &SequenceSet{
+		text:   text,
+		names:  sr.names,
+		starts: starts,
+	}, nil
+}
+
+// Close frees the arrays if Finish has not been called.
+func (sr *sequenceReader) Close() error {
+	err := sr.symbols.Close()
+	if err2 := sr.starts.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// dnaBase returns the symbol for one of the bases A, C, G, T, or N, in
+// either case, or for U, which is read as T.
+func dnaBase(ch byte) (uint64, bool) {
+	switch ch {
+	case 'A', 'a':
+		return BaseA, true
+	case 'C', 'c':
+		return BaseC, true
+	case 'G', 'g':
+		return BaseG, true
+	case 'N', 'n':
+		return BaseN, true
+	case 'T', 't', 'U', 'u':
+		return BaseT, true
+	}
+	return 0, false
+}
+
+// Text returns the underlying Text of DNA symbols, suitable for passing to
+// BuildSuffixArray and friends.
+func (seqs *SequenceSet) Text() *Text { return seqs.text }
+
+// NumRecords returns the number of records.
+func (seqs *SequenceSet) NumRecords() uint64 { return uint64(len(seqs.names)) }
+
+// RecordName returns the name of the given record.
+func (seqs *SequenceSet) RecordName(record uint64) (string, error) {
+	if record >= seqs.NumRecords() {
+		return "", fmt.Errorf("SequenceSet.RecordName: record %d is out of range [0, %d)", record, seqs.NumRecords())
+	}
+	return seqs.names[record], nil
+}
+
+// RecordStart returns the text offset at which the given record begins.
+func (seqs *SequenceSet) RecordStart(record uint64) (uint64, error) {
+	if record >= seqs.NumRecords() {
+		return 0, fmt.Errorf("SequenceSet.RecordStart: record %d is out of range [0, %d)", record, seqs.NumRecords())
+	}
+	return seqs.starts.ValueAt(record)
+}
+
+// RecordLen returns the number of bases in the given record.
+func (seqs *SequenceSet) RecordLen(record uint64) (uint64, error) {
+	start, err := seqs.RecordStart(record)
+	if err != nil {
+		return 0, err
+	}
+	end := seqs.text.Len()
+	if record+1 < seqs.NumRecords() {
+		if end, err = seqs.starts.ValueAt(record + 1); err != nil {
+			return 0, err
+		}
+		end--
+	}
+	return end - start, nil
+}
+
+// Locate maps a text offset to the record containing it and the offset of
+// the base within that record.  A separator belongs to the record which it
+// ends, at the offset just past its last base.
+func (seqs *SequenceSet) Locate(offset uint64) (SequenceHit, error) {
+	if seqs.NumRecords() == 0 || offset > seqs.text.Len() {
+		return SequenceHit{}, fmt.Errorf("SequenceSet.Locate: offset %d is out of range for text of length %d", offset, seqs.text.Len())
+	}
+	next, err := searchIndices(0, seqs.starts.Len(), func(index uint64) (bool, error) {
+		start, err := seqs.starts.ValueAt(index)
+		return start > offset, err
+	})
+	if err != nil {
+		return SequenceHit{}, err
+	}
+	start, err := seqs.starts.ValueAt(next - 1)
+	return SequenceHit{Record: next - 1, Position: offset - start}, err
+}
+
+// Symbols converts a DNA string into the equivalent list of symbols.  Only
+// the bases A, C, G, T, U, and N are accepted, in either case; returns false
+// for anything else.  N only matches N in the text.
+func (seqs *SequenceSet) Symbols(seq string) ([]uint64, bool) {
+	out := make([]uint64, len(seq))
+	for i := 0; i < len(seq); i++ {
+		symbol, ok := dnaBase(seq[i])
+		if !ok {
+			return nil, false
+		}
+		out[i] = symbol
+	}
+	return out, true
+}
+
+// Close frees the resources used by the SequenceSet, including its Text.
+func (seqs *SequenceSet) Close() error {
+	err := seqs.text.Close()
+	if err2 := seqs.starts.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// SequenceIndex is a DocumentIndex over a SequenceSet, whose documents are
+// the records.
+type SequenceIndex struct {
+	seqs *SequenceSet
+	di   *DocumentIndex
+}
+
+// BuildSequenceIndex constructs an Index and DocumentIndex over the Text of a
+// SequenceSet.  The SequenceIndex takes ownership of the SequenceSet: closing
+// the SequenceIndex also closes the SequenceSet.
+func BuildSequenceIndex(seqs *SequenceSet, opts ...Option) (*SequenceIndex, error) {
+	idx, err := BuildIndex(seqs.text, opts...)
+	if err != nil {
+		return nil, err
+	}
+	di, err := BuildDocumentIndex(idx, opts...)
+	if err != nil {
+		idx.closeArrays()
+		return nil, err
+	}
+	return &SequenceIndex{seqs: seqs, di: di}, nil
+}
+
+// Sequences returns the indexed SequenceSet.
+func (si *SequenceIndex) Sequences() *SequenceSet { return si.seqs }
+
+// DocumentIndex returns the underlying DocumentIndex.
+func (si *SequenceIndex) DocumentIndex() *DocumentIndex { return si.di }
+
+// Close frees the resources used by the SequenceIndex, including its
+// SequenceSet.
+func (si *SequenceIndex) Close() error {
+	err := si.di.Close()
+	if err2 := si.seqs.starts.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// SearchSequence returns every occurrence of a DNA string in the indexed
+// records, ordered by record and then by position.  The string is converted
+// as by SequenceSet.Symbols; if it has any other character, there are no
+// matches.
+func SearchSequence(si *SequenceIndex, seq string) ([]SequenceHit, error) {
+	symbols, ok := si.seqs.Symbols(seq)
+	if !ok {
+		return nil, nil
+	}
+
+	idx := si.di.idx
+	offsets, err := searchSymbols(idx.searchText(), idx.sa, idx.lcplr, symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]SequenceHit, len(offsets))
+	for i, offset := range offsets {
+		if hits[i], err = si.locate(offset); err != nil {
+			return nil, err
+		}
+	}
+	return hits, nil
+}
+
+// locate maps a text offset to a record and position using the document
+// array of the DocumentIndex.
+func (si *SequenceIndex) locate(offset uint64) (SequenceHit, error) {
+	doc, err := si.di.DocumentAt(offset)
+	if err != nil {
+		return SequenceHit{}, err
+	}
+	start, err := si.di.DocumentStart(doc)
+	return SequenceHit{Record: doc, Position: offset - start}, err
+}
+
+// SequenceRecords returns, in ascending order, the records in which a DNA
+// string occurs, as found by DocumentListing.
+func SequenceRecords(si *SequenceIndex, seq string) ([]uint64, error) {
+	symbols, ok := si.seqs.Symbols(seq)
+	if !ok {
+		return nil, nil
+	}
+	return documentListing(si.di, symbols)
+}
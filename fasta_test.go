@@ -0,0 +1,209 @@
+package suffixarray
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+const sampleFASTA = `>chr1 first chromosome
+ACGTACGTNN
+nnGATTACA
+; a comment
+>chr2
+gattaca
+RYacgu
+
+>empty
+>chr3 last
+TTTTGATTACAT`
+
+const sampleFASTQ = "@read1 lane=1\nACGTGATTACA\n+\nIIIIIIIIIII\n@read2\r\nGATT\r\nACAT\r\n+read2\r\n@@@@\r\n!!!!\r\n@read3\nNNNN\n+\n####\n"
+
+func TestReadFASTA(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := extendOptions(cfg.Opts, PackedSymbols())
+
+		seqs, err := ReadFASTA(strings.NewReader(sampleFASTA), opts...)
+		if err != nil {
+			t.Errorf("[%s] ReadFASTA: error: %v", cfg.Name, err)
+			continue
+		}
+
+		var records []string
+		for i := uint64(0); i < seqs.NumRecords(); i++ {
+			start, err := seqs.RecordStart(i)
+			if err != nil {
+				t.Errorf("[%s] RecordStart %d: error: %v", cfg.Name, i, err)
+			}
+			length, err := seqs.RecordLen(i)
+			if err != nil {
+				t.Errorf("[%s] RecordLen %d: error: %v", cfg.Name, i, err)
+			}
+			name, err := seqs.RecordName(i)
+			if err != nil {
+				t.Errorf("[%s] RecordName %d: error: %v", cfg.Name, i, err)
+			}
+			records = append(records, fmt.Sprintf("%s@%d+%d", name, start, length))
+		}
+		if _, err := seqs.RecordName(seqs.NumRecords()); err == nil {
+			t.Errorf("[%s] RecordName: expected error for record %d", cfg.Name, seqs.NumRecords())
+		}
+		if _, ok := seqs.Text().ba.(*packedStorage); !ok {
+			t.Errorf("[%s] Text: expected packed symbols, got %T", cfg.Name, seqs.Text().ba)
+		}
+		if expected, actual := "chr1@0+19 chr2@20+13 empty@34+0 chr3@35+12", strings.Join(records, " "); expected != actual {
+			t.Errorf("[%s] records: expected %s, got %s", cfg.Name, expected, actual)
+		}
+
+		const bases = "\x00ACGNT"
+		var buf strings.Builder
+		seqs.Text().ForEach(func(index, symbol uint64) error {
+			if symbol == DocumentSeparator {
+				buf.WriteByte('|')
+			} else {
+				buf.WriteByte(bases[symbol])
+			}
+			return nil
+		})
+		if expected, actual := "ACGTACGTNNNNGATTACA|GATTACANNACGT||TTTTGATTACAT", buf.String(); expected != actual {
+			t.Errorf("[%s] Text: expected %s, got %s", cfg.Name, expected, actual)
+		}
+
+		for _, q := range [][3]uint64{{0, 0, 0}, {19, 0, 19}, {20, 1, 0}, {34, 2, 0}, {46, 3, 11}, {47, 3, 12}} {
+			hit, err := seqs.Locate(q[0])
+			if err != nil || hit.Record != q[1] || hit.Position != q[2] {
+				t.Errorf("[%s] Locate %d: expected {%d %d}, got %v, %v", cfg.Name, q[0], q[1], q[2], hit, err)
+			}
+		}
+
+		si, err := BuildSequenceIndex(seqs, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildSequenceIndex: error: %v", cfg.Name, err)
+			seqs.Close()
+			continue
+		}
+
+		for _, q := range []struct {
+			seq     string
+			hits    string
+			records string
+		}{
+			{"GATTACA", "[{0 12} {1 0} {3 4}]", "[0 1 3]"},
+			{"gattacat", "[{3 4}]", "[3]"},
+			{"ACGT", "[{0 0} {0 4} {1 9}]", "[0 1]"},
+			{"NN", "[{0 8} {0 9} {0 10} {1 7}]", "[0 1]"},
+			{"CAG", "[]", "[]"},
+			{"AXA", "[]", "[]"},
+		} {
+			hits, err := SearchSequence(si, q.seq)
+			if err != nil {
+				t.Errorf("[%s] SearchSequence %q: error: %v", cfg.Name, q.seq, err)
+			} else if actual := fmt.Sprintf("%v", hits); actual != q.hits {
+				t.Errorf("[%s] SearchSequence %q: expected %s, got %s", cfg.Name, q.seq, q.hits, actual)
+			}
+
+			records, err := SequenceRecords(si, q.seq)
+			if err != nil {
+				t.Errorf("[%s] SequenceRecords %q: error: %v", cfg.Name, q.seq, err)
+			} else if actual := fmt.Sprintf("%v", records); actual != q.records {
+				t.Errorf("[%s] SequenceRecords %q: expected %s, got %s", cfg.Name, q.seq, q.records, actual)
+			}
+		}
+
+		if err := si.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
+
+func TestReadFASTA_WithFile(t *testing.T) {
+	expected, err := ReadFASTA(strings.NewReader(sampleFASTA))
+	if err != nil {
+		t.Fatalf("ReadFASTA: error: %v", err)
+	}
+	defer expected.Close()
+
+	f, err := ioutil.TempFile("", "fasta")
+	if err != nil {
+		t.Fatalf("TempFile: error: %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	// go-bigarray expects the file to have room for every value, and the
+	// DNA alphabet takes one byte per value.
+	n := expected.Text().Len()
+	if err := f.Truncate(int64(n)); err != nil {
+		f.Close()
+		t.Fatalf("Truncate: error: %v", err)
+	}
+
+	seqs, err := ReadFASTA(strings.NewReader(sampleFASTA), WithFile(f))
+	if err != nil {
+		f.Close()
+		t.Fatalf("ReadFASTA: error: %v", err)
+	}
+	if expected, actual := expected.Text().Debug(), seqs.Text().Debug(); expected != actual {
+		t.Errorf("Text: expected %s, got %s", expected, actual)
+	}
+
+	if err := seqs.Text().Flush(); err != nil {
+		t.Errorf("Flush: error: %v", err)
+	}
+	data, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("ReadFile: error: %v", err)
+	}
+	var actual []uint64
+	for _, b := range data {
+		actual = append(actual, uint64(b))
+	}
+	if expected, actual := expected.Text().Debug(), fmt.Sprintf("%v", actual); expected != actual {
+		t.Errorf("WithFile: expected %s in the file, got %s", expected, actual)
+	}
+
+	if err := seqs.Close(); err != nil {
+		t.Errorf("Close: error: %v", err)
+	}
+}
+
+func TestReadFASTQ(t *testing.T) {
+	seqs, err := ReadFASTQ(strings.NewReader(sampleFASTQ))
+	if err != nil {
+		t.Fatalf("ReadFASTQ: error: %v", err)
+	}
+	var records []string
+	for i := uint64(0); i < seqs.NumRecords(); i++ {
+		start, _ := seqs.RecordStart(i)
+		length, _ := seqs.RecordLen(i)
+		name, _ := seqs.RecordName(i)
+		records = append(records, fmt.Sprintf("%s@%d+%d", name, start, length))
+	}
+	if expected, actual := "read1@0+11 read2@12+8 read3@21+4", strings.Join(records, " "); expected != actual {
+		t.Errorf("records: expected %s, got %s", expected, actual)
+	}
+	if err := seqs.Close(); err != nil {
+		t.Errorf("Close: error: %v", err)
+	}
+
+	for _, bad := range []string{
+		"ACGT\n",
+		"@r\nACGT\n",
+		"@r\nACGT\n+\nIII\n",
+		"@r\nACXT\n+\nIIII\n",
+	} {
+		if _, err := ReadFASTQ(strings.NewReader(bad)); err == nil || !strings.HasPrefix(err.Error(), "ReadFASTQ: ") {
+			t.Errorf("ReadFASTQ %q: expected a ReadFASTQ error, got %v", bad, err)
+		}
+	}
+	expected := `ReadFASTA: line 2: invalid base 'X'`
+	if _, err := ReadFASTA(strings.NewReader(">r\nACXT\n")); err == nil || err.Error() != expected {
+		t.Errorf("ReadFASTA: expected error %q, got %v", expected, err)
+	}
+	if _, err := ReadFASTA(strings.NewReader("ACGT\n>r\n")); err == nil {
+		t.Errorf("ReadFASTA: expected error for sequence before header")
+	}
+}
@@ -0,0 +1,91 @@
+package suffixarray
+
+// foldSymbol maps the ASCII letters A-Z to a-z, and leaves every other
+// symbol unchanged.
+func foldSymbol(symbol uint64) uint64 {
+	if symbol >= 'A' && symbol <= 'Z' {
+		return symbol + ('a' - 'A')
+	}
+	return symbol
+}
+
+func foldSymbols(symbols []uint64) []uint64 {
+	out := make([]uint64, len(symbols))
+	for i, symbol := range symbols {
+		out[i] = foldSymbol(symbol)
+	}
+	return out
+}
+
+// foldElement returns an element which, in addition to the symbols matched
+// by elem, matches the folded form of each of them.
+func foldElement(elem PatternElement) PatternElement {
+	out := make(PatternElement, len(elem), 2*len(elem))
+	copy(out, elem)
+	for _, r := range elem {
+		first, last := r.First, r.Last
+		if first < 'A' {
+			first = 'A'
+		}
+		if last > 'Z' {
+			last = 'Z'
+		}
+		if first <= last {
+			out = append(out, SymbolRange{foldSymbol(first), foldSymbol(last)})
+		}
+	}
+	return out
+}
+
+// foldText returns a copy of text with ASCII letters folded to lower case.
+func foldText(text *Text, opts []Option) (*Text, error) {
+	opts = extendOptions(
+		opts,
+		NumValues(text.Len()))
+
+	folded, err := NewText(text.AlphabetSize(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	src := text.Iterate(0, text.Len())
+	dst := folded.Iterate(0, folded.Len())
+	for src.Next() && dst.Next() {
+		dst.SetSymbol(foldSymbol(src.Symbol()))
+	}
+	err = src.Close()
+	if err2 := dst.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		folded.Close()
+		return nil, err
+	}
+	return folded, nil
+}
+
+// SearchFold returns the list of offsets into the indexed text which begin
+// with the given phrase, ignoring the case of ASCII letters.
+//
+// On an Index built with the FoldCase option, this is an ordinary search for
+// the folded phrase.  Otherwise, each letter of the phrase is treated as a
+// class matching both of its cases, and the suffix array is explored as for
+// SearchPattern, branching wherever both cases occur in the text.
+//
+func SearchFold(idx *Index, phrase string) ([]uint64, error) {
+	if idx.FoldCase() {
+		return SearchIndex(idx, phrase)
+	}
+
+	pattern := make(Pattern, len(phrase))
+	for i := 0; i < len(phrase); i++ {
+		lower := foldSymbol(uint64(phrase[i]))
+		elem := PatternElement{{lower, lower}}
+		if lower >= 'a' && lower <= 'z' {
+			upper := lower - ('a' - 'A')
+			elem = append(elem, SymbolRange{upper, upper})
+		}
+		pattern[i] = elem
+	}
+	return SearchPattern(idx, pattern)
+}
@@ -0,0 +1,110 @@
+package suffixarray
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSearchFold(t *testing.T) {
+	lowered := strings.ToLower(sampleText)
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+
+		for _, foldCase := range []bool{false, true} {
+			name := fmt.Sprintf("%s/%v", cfg.Name, foldCase)
+			opts := cfg.Opts
+			if foldCase {
+				opts = extendOptions(opts, FoldCase())
+			}
+
+			idx, err := BuildIndex(NewTextFromString(sampleText, cfg.Opts...), opts...)
+			if err != nil {
+				t.Errorf("[%s] BuildIndex: error: %v", name, err)
+				continue
+			}
+			if idx.FoldCase() != foldCase {
+				t.Errorf("[%s] FoldCase: expected %v, got %v", name, foldCase, idx.FoldCase())
+			}
+			if expected, actual := NewTextFromString(sampleText, cfg.Opts...).Debug(), idx.Text().Debug(); expected != actual {
+				t.Errorf("[%s] Text: expected the original text", name)
+			}
+
+			for _, phrase := range []string{"Lorem", "lorem", "LOREM IPSUM", "phasellus NEC", "odio.", "zzz"} {
+				offsets, err := SearchFold(idx, phrase)
+				if err != nil {
+					t.Errorf("[%s] SearchFold %q: error: %v", name, phrase, err)
+					continue
+				}
+				expected := fmt.Sprintf("%v", NaiveSearch(lowered, strings.ToLower(phrase)))
+				actual := fmt.Sprintf("%v", offsets)
+				if expected != actual {
+					t.Errorf("[%s] SearchFold %q: expected %s, got %s", name, phrase, expected, actual)
+				}
+			}
+
+			if foldCase {
+				offsets, err := SearchIndex(idx, "PHASELLUS")
+				if err != nil {
+					t.Errorf("[%s] SearchIndex: error: %v", name, err)
+				}
+				expected := fmt.Sprintf("%v", NaiveSearch(lowered, "phasellus"))
+				actual := fmt.Sprintf("%v", offsets)
+				if expected != actual {
+					t.Errorf("[%s] SearchIndex: expected %s, got %s", name, expected, actual)
+				}
+
+				for _, expr := range []string{`Phasellus [a-z]+`, `PHASELLUS [a-z]+`, `(?i)PHASELLUS [a-z]+`} {
+					re := regexp.MustCompile(expr)
+					matches, err := SearchRegexp(idx, re)
+					if err != nil {
+						t.Errorf("[%s] SearchRegexp %q: error: %v", name, expr, err)
+					}
+					expected = fmt.Sprintf("%v", NaiveSearchRegexp(sampleText, re))
+					actual = fmt.Sprintf("%v", matches)
+					if expected != actual {
+						t.Errorf("[%s] SearchRegexp %q: expected %s, got %s", name, expr, expected, actual)
+					}
+				}
+
+				completions, err := Complete(idx, "PHASELLUS ", 3, 4)
+				if err != nil {
+					t.Errorf("[%s] Complete: error: %v", name, err)
+				}
+				expected = fmt.Sprintf("%v", NaiveComplete(lowered, "phasellus ", 3, 4))
+				actual = fmt.Sprintf("%v", completions)
+				if expected != actual {
+					t.Errorf("[%s] Complete: expected %s, got %s", name, expected, actual)
+				}
+			}
+
+			if err := idx.Close(); err != nil {
+				t.Errorf("[%s] Close: error: %v", name, err)
+			}
+		}
+	}
+}
+
+func TestBuildIndex_FoldCaseLargeAlphabet(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		text, err := NewText(512, extendOptions(opts, NumValues(uint64(len(sampleText))))...)
+		if err != nil {
+			t.Errorf("[%s] NewText: error: %v", cfg.Name, err)
+			continue
+		}
+
+		if idx, err := BuildIndex(text, extendOptions(opts, FoldCase())...); err == nil {
+			t.Errorf("[%s] BuildIndex: expected error for alphabet size 512", cfg.Name)
+			idx.Close()
+			continue
+		}
+
+		if err := text.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
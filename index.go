@@ -0,0 +1,501 @@
+package suffixarray
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	bigarray "github.com/team-spectre/go-bigarray"
+)
+
+// Index bundles a Text together with the arrays needed to search it: its
+// suffix array, LCP array, and LCP-LR array.
+type Index struct {
+	text   *Text
+	folded *Text
+	sa     *SuffixArray
+	lcp    *LCPArray
+	lcplr  Storage
+	opts   []Option
+
+	// Auxiliary arrays which only some searches need, built on first use.
+	mu      sync.Mutex
+	rank    Storage
+	lcpRMQ  *rangeMinimum
+	wavelet *waveletMatrix
+}
+
+// BuildIndex constructs the suffix array, LCP array, and LCP-LR array for a
+// Text.  The Index takes ownership of the Text: closing the Index also closes
+// the Text.
+//
+// If the FoldCase option is given, the arrays are instead built over a copy
+// of the Text with ASCII letters folded to lower case, and searches for
+// phrases and patterns on the Index ignore case.  Text still returns the
+// original.  SearchRegexp still matches as the regexp itself says, so a
+// regexp must use (?i) to ignore case, and Complete returns continuations in
+// their folded form, since it merges the case variants of each one.  FoldCase
+// only applies to texts of bytes, and BuildIndex returns an error if it is
+// given for a larger alphabet, whose symbols 'A' to 'Z' are not letters.
+//
+func BuildIndex(text *Text, opts ...Option) (*Index, error) {
+	idx := &Index{text: text, opts: opts}
+
+	needClose := true
+	defer func() {
+		if needClose {
+			idx.closeArrays()
+		}
+	}()
+
+	var err error
+	if makeBuildOptions(opts).foldCase {
+		if text.AlphabetSize() > 256 {
+			return nil, fmt.Errorf("BuildIndex: FoldCase: alphabet size %d exceeds 256", text.AlphabetSize())
+		}
+		idx.folded, err = foldText(text, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	idx.sa, err = BuildSuffixArray(idx.searchText(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.lcp, err = BuildLCPArray(idx.searchText(), idx.sa, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.lcplr, err = buildLCPLRArray(idx.lcp, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	needClose = false
+	return idx, nil
+}
+
+// Text returns the indexed Text.
+func (idx *Index) Text() *Text { return idx.text }
+
+// SuffixArray returns the suffix array of the Text.
+func (idx *Index) SuffixArray() *SuffixArray { return idx.sa }
+
+// LCPArray returns the LCP array of the Text.
+func (idx *Index) LCPArray() *LCPArray { return idx.lcp }
+
+// LCPLR returns the LCP-LR array of the Text.
+func (idx *Index) LCPLR() bigarray.BigArray { return storageToBigArray(idx.lcplr) }
+
+// Len returns the length of the indexed Text.
+func (idx *Index) Len() uint64 { return idx.text.Len() }
+
+// FoldCase returns true iff the Index was built with the FoldCase option.
+func (idx *Index) FoldCase() bool { return idx.folded != nil }
+
+// searchText returns the Text over which the arrays were built.
+func (idx *Index) searchText() *Text {
+	if idx.folded != nil {
+		return idx.folded
+	}
+	return idx.text
+}
+
+// prepare converts a phrase into the form in which it can be found in
+// searchText.
+func (idx *Index) prepare(phrase []uint64) []uint64 {
+	if idx.folded != nil {
+		return foldSymbols(phrase)
+	}
+	return phrase
+}
+
+// rankArray returns the inverse of the suffix array, which maps each text
+// offset to the index of its suffix.  It is built on first use.
+func (idx *Index) rankArray() (Storage, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.rank != nil {
+		return idx.rank, nil
+	}
+
+	rankOpts := extendOptions(
+		idx.opts,
+		NumValues(idx.sa.Len()),
+		MaxValue(idx.sa.Len()-1),
+		WithFile(nil))
+
+	rank, err := makeStorage(rankOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = idx.sa.ForEach(func(index uint64, pos uint64) error {
+		return rank.SetValueAt(pos, index)
+	})
+	if err != nil {
+		rank.Close()
+		return nil, err
+	}
+
+	idx.rank = rank
+	return rank, nil
+}
+
+// lcpRangeMinimum returns a rangeMinimum over the LCP array, which finds the
+// smallest height within any range of the suffix array.  It is built on first
+// use.
+func (idx *Index) lcpRangeMinimum() (*rangeMinimum, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.lcpRMQ != nil {
+		return idx.lcpRMQ, nil
+	}
+
+	rm, err := buildRangeMinimum(idx.lcp.ba, idx.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.lcpRMQ = rm
+	return rm, nil
+}
+
+// waveletMatrix returns a waveletMatrix over the suffix array, which finds
+// the suffixes within a range of the suffix array that start within a range
+// of the text.  It is built on first use.
+func (idx *Index) waveletMatrix() (*waveletMatrix, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.wavelet != nil {
+		return idx.wavelet, nil
+	}
+
+	wm, err := buildWaveletMatrix(idx.sa.ba, idx.sa.Len()-1, idx.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.wavelet = wm
+	return wm, nil
+}
+
+// Close frees the resources used by the Index, including its Text.
+func (idx *Index) Close() error {
+	err := idx.closeArrays()
+	if err2 := idx.text.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (idx *Index) closeArrays() error {
+	var finalError error
+	if idx.wavelet != nil {
+		if err := idx.wavelet.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	if idx.lcpRMQ != nil {
+		if err := idx.lcpRMQ.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	if idx.rank != nil {
+		if err := idx.rank.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	if idx.lcplr != nil {
+		if err := idx.lcplr.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	if idx.lcp != nil {
+		if err := idx.lcp.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	if idx.sa != nil {
+		if err := idx.sa.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	if idx.folded != nil {
+		if err := idx.folded.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	return finalError
+}
+
+// SearchIndex is equivalent to Search on the Index's Text, SuffixArray, and
+// LCP-LR array.
+func SearchIndex(idx *Index, phrase string) ([]uint64, error) {
+	return searchSymbols(idx.searchText(), idx.sa, idx.lcplr, idx.prepare(stringToSymbols(phrase)))
+}
+
+// IncrementalIndex is an index over a text which grows over time, such as a
+// log file.
+//
+// Appended text is indexed on its own, as a new segment, so the cost of an
+// append is proportional to the size of the new text rather than the whole
+// corpus.  Searches consult every segment and also check for matches which
+// straddle segment boundaries, so the results are identical to those of a
+// single Index built over the concatenated text.  Because every segment adds
+// to the cost of a search, segments should periodically be merged by calling
+// Compact.
+//
+// All methods are safe to call concurrently, and searches run in parallel
+// with one another.  Compact copies and indexes the text without holding any
+// lock which searches or appends need, so it may be run in a background
+// goroutine; they only wait while it swaps in the merged segment.
+//
+type IncrementalIndex struct {
+	mu        sync.RWMutex
+	compactMu sync.Mutex
+	alphaSize uint64
+	opts      []Option
+	segments  []*Index
+	bases     []uint64
+	length    uint64
+}
+
+// NewIncrementalIndex constructs an empty IncrementalIndex for texts over the
+// given alphabet.  The options are used when building each segment.
+func NewIncrementalIndex(alphaSize uint64, opts ...Option) *IncrementalIndex {
+	return &IncrementalIndex{alphaSize: alphaSize, opts: opts}
+}
+
+// Len returns the total length of the indexed text.
+func (idx *IncrementalIndex) Len() uint64 {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.length
+}
+
+// NumSegments returns the number of segments which have not yet been merged
+// by Compact.
+func (idx *IncrementalIndex) NumSegments() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.segments)
+}
+
+// Append indexes the given Text and adds it to the end of the indexed text.
+// The IncrementalIndex takes ownership of the Text.
+func (idx *IncrementalIndex) Append(text *Text) error {
+	if text.AlphabetSize() > idx.alphaSize {
+		text.Close()
+		return fmt.Errorf("IncrementalIndex.Append: alphabet size %d exceeds %d", text.AlphabetSize(), idx.alphaSize)
+	}
+	if text.Len() == 0 {
+		return text.Close()
+	}
+
+	segment, err := BuildIndex(text, idx.opts...)
+	if err != nil {
+		text.Close()
+		return err
+	}
+
+	idx.mu.Lock()
+	idx.segments = append(idx.segments, segment)
+	idx.bases = append(idx.bases, idx.length)
+	idx.length += text.Len()
+	idx.mu.Unlock()
+	return nil
+}
+
+// Compact merges all current segments into one.  Segments appended while the
+// compaction is in progress are left as they are.
+func (idx *IncrementalIndex) Compact() error {
+	idx.compactMu.Lock()
+	defer idx.compactMu.Unlock()
+
+	// Only Compact and Close ever close a segment, and both hold
+	// idx.compactMu, so the snapshot stays valid after idx.mu is released.
+	idx.mu.RLock()
+	segments := idx.segments
+	bases := idx.bases
+	idx.mu.RUnlock()
+
+	numMerged := len(segments)
+	if numMerged <= 1 {
+		return nil
+	}
+	merged, err := concatenateSegments(segments, bases, idx.alphaSize, idx.opts)
+	if err != nil {
+		return err
+	}
+
+	segment, err := BuildIndex(merged, idx.opts...)
+	if err != nil {
+		merged.Close()
+		return err
+	}
+
+	idx.mu.Lock()
+	old := idx.segments[:numMerged]
+	idx.segments = append([]*Index{segment}, idx.segments[numMerged:]...)
+	idx.bases = append([]uint64{0}, idx.bases[numMerged:]...)
+	idx.mu.Unlock()
+
+	var finalError error
+	for _, segment := range old {
+		if err := segment.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	return finalError
+}
+
+// concatenateSegments copies the text of the given segments, which begin at
+// the given bases, into a new Text.
+func concatenateSegments(segments []*Index, bases []uint64, alphaSize uint64, opts []Option) (*Text, error) {
+	n := len(segments)
+	length := bases[n-1] + segments[n-1].Len()
+
+	opts = extendOptions(
+		opts,
+		NumValues(length))
+
+	text, err := NewText(alphaSize, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := text.Iterate(0, text.Len())
+	for _, segment := range segments {
+		// Searches may be reading the segment concurrently, so it is
+		// read through random access rather than an iterator.
+		for i := uint64(0); i < segment.text.Len() && dst.Next(); i++ {
+			symbol, err := segment.text.SymbolAt(i)
+			if err != nil {
+				dst.Close()
+				text.Close()
+				return nil, err
+			}
+			dst.SetSymbol(symbol)
+		}
+	}
+	if err := dst.Close(); err != nil {
+		text.Close()
+		return nil, err
+	}
+	return text, nil
+}
+
+// Close frees the resources used by all segments.
+func (idx *IncrementalIndex) Close() error {
+	idx.compactMu.Lock()
+	defer idx.compactMu.Unlock()
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	var finalError error
+	for _, segment := range idx.segments {
+		if err := segment.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	idx.segments = nil
+	idx.bases = nil
+	idx.length = 0
+	return finalError
+}
+
+// matchesAt returns true iff the phrase occurs at the given offset of the
+// concatenated text.  The caller must hold idx.mu for reading.
+func (idx *IncrementalIndex) matchesAt(pos uint64, phrase []uint64) (bool, error) {
+	k := sort.Search(len(idx.bases), func(i int) bool { return idx.bases[i] > pos }) - 1
+	i := 0
+	for i < len(phrase) && k < len(idx.segments) {
+		text := idx.segments[k].searchText()
+		for i < len(phrase) && pos-idx.bases[k] < text.Len() {
+			symbol, err := text.SymbolAt(pos - idx.bases[k])
+			if err != nil || symbol != phrase[i] {
+				return false, err
+			}
+			i++
+			pos++
+		}
+		k++
+	}
+	return i == len(phrase), nil
+}
+
+// SearchIncremental returns the list of offsets into the concatenated text
+// which begin with the given phrase.
+func SearchIncremental(idx *IncrementalIndex, phrase string) ([]uint64, error) {
+	return SearchIncrementalSymbols(idx, stringToSymbols(phrase))
+}
+
+// SearchIncrementalSymbols is like SearchIncremental, but the phrase is given
+// as a list of symbols.
+func SearchIncrementalSymbols(idx *IncrementalIndex, phrase []uint64) ([]uint64, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if makeBuildOptions(idx.opts).foldCase {
+		phrase = foldSymbols(phrase)
+	}
+
+	var results []uint64
+	m := uint64(len(phrase))
+	for k, segment := range idx.segments {
+		base := idx.bases[k]
+		lo, hi, err := rangeSymbols(segment.searchText(), segment.sa, segment.lcplr, phrase)
+		if err != nil {
+			return nil, err
+		}
+		for i := lo; i < hi; i++ {
+			pos, err := segment.sa.PositionAt(i)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, base+pos)
+		}
+
+		// Matches which begin in this segment but run past its end
+		// are invisible to the segment's own suffix array.
+		end := base + segment.Len()
+		if k == len(idx.segments)-1 || m < 2 {
+			continue
+		}
+		start := base
+		if end-start > m-1 {
+			start = end - (m - 1)
+		}
+		for pos := start; pos < end; pos++ {
+			ok, err := idx.matchesAt(pos, phrase)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				results = append(results, pos)
+			}
+		}
+	}
+
+	sort.Sort(byU64(results))
+
+	// Each segment reports its own end as a match for the empty phrase.
+	out := results[:0]
+	for i, pos := range results {
+		if i == 0 || pos != results[i-1] {
+			out = append(out, pos)
+		}
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
@@ -0,0 +1,117 @@
+package suffixarray
+
+import (
+	"fmt"
+	"testing"
+)
+
+var incrementalPhrases = []string{
+	searchPhrase,
+	"dolor sit amet",
+	"a",
+	"is",
+	"Phasellus nec",
+	"in nunc. Phasellus",
+	"zzz",
+}
+
+func checkIncrementalSearch(t *testing.T, name string, idx *IncrementalIndex, text string) {
+	t.Helper()
+	if expected, actual := uint64(len(text)), idx.Len(); expected != actual {
+		t.Errorf("[%s] Len: expected %d, got %d", name, expected, actual)
+	}
+	for _, phrase := range incrementalPhrases {
+		offsets, err := SearchIncremental(idx, phrase)
+		if err != nil {
+			t.Errorf("[%s] SearchIncremental %q: error: %v", name, phrase, err)
+			continue
+		}
+		expected := fmt.Sprintf("%v", NaiveSearch(text, phrase))
+		actual := fmt.Sprintf("%v", offsets)
+		if expected != actual {
+			t.Errorf("[%s] SearchIncremental %q: expected %s, got %s", name, phrase, expected, actual)
+		}
+	}
+}
+
+// appendChunks appends the text to the index as a series of segments of the
+// given size.
+func appendChunks(idx *IncrementalIndex, text string, chunkSize int, opts []Option) error {
+	for i := 0; i < len(text); i += chunkSize {
+		j := i + chunkSize
+		if j > len(text) {
+			j = len(text)
+		}
+		if err := idx.Append(NewTextFromString(text[i:j], opts...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestIncrementalIndex(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		for _, chunkSize := range []int{1, 5, 37, 500, len(sampleText)} {
+			name := fmt.Sprintf("%s/%d", cfg.Name, chunkSize)
+			idx := NewIncrementalIndex(256, opts...)
+
+			half := len(sampleText) / 2
+			if err := appendChunks(idx, sampleText[:half], chunkSize, opts); err != nil {
+				t.Errorf("[%s] Append: error: %v", name, err)
+				idx.Close()
+				continue
+			}
+			checkIncrementalSearch(t, name, idx, sampleText[:half])
+
+			if err := idx.Compact(); err != nil {
+				t.Errorf("[%s] Compact: error: %v", name, err)
+				idx.Close()
+				continue
+			}
+			if expected, actual := 1, idx.NumSegments(); expected != actual {
+				t.Errorf("[%s] NumSegments: expected %d, got %d", name, expected, actual)
+			}
+			checkIncrementalSearch(t, name, idx, sampleText[:half])
+
+			if err := appendChunks(idx, sampleText[half:], chunkSize, opts); err != nil {
+				t.Errorf("[%s] Append: error: %v", name, err)
+				idx.Close()
+				continue
+			}
+			checkIncrementalSearch(t, name, idx, sampleText)
+
+			if err := idx.Close(); err != nil {
+				t.Errorf("[%s] Close: error: %v", name, err)
+			}
+		}
+	}
+}
+
+func TestIncrementalIndex_BackgroundCompact(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		idx := NewIncrementalIndex(256, opts...)
+		if err := appendChunks(idx, sampleText, 64, opts); err != nil {
+			t.Errorf("[%s] Append: error: %v", cfg.Name, err)
+			idx.Close()
+			continue
+		}
+
+		done := make(chan error)
+		go func() { done <- idx.Compact() }()
+		checkIncrementalSearch(t, cfg.Name, idx, sampleText)
+		if err := <-done; err != nil {
+			t.Errorf("[%s] Compact: error: %v", cfg.Name, err)
+		}
+		checkIncrementalSearch(t, cfg.Name, idx, sampleText)
+
+		if err := idx.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
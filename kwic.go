@@ -0,0 +1,160 @@
+package suffixarray
+
+import (
+	"errors"
+	"sort"
+)
+
+// SnippetOptions controls the windows of context returned by Snippets.
+type SnippetOptions struct {
+	// Before and After give the amount of context to include before and
+	// after each hit: a number of symbols, or with WholeLines, a number of
+	// lines.
+	Before uint64
+	After  uint64
+
+	// WholeLines widens each window to the start of the line on which the
+	// hit begins and the end of the line on which it ends, not including
+	// the newline.  It requires a LineIndex.
+	WholeLines bool
+
+	// Merge combines windows which overlap or touch into one Snippet
+	// holding all of their hits.
+	Merge bool
+
+	// LineIndex, if not nil, is used to fill in the line and column
+	// numbers of each hit.
+	LineIndex *LineIndex
+}
+
+// SnippetHit locates one hit within a Snippet.
+type SnippetHit struct {
+	// Offset is the text offset of the hit.
+	Offset uint64
+
+	// Line and Column give the 1-based position of the hit, or 0 if no
+	// LineIndex was provided.
+	Line   uint64
+	Column uint64
+}
+
+// Snippet is a window of text surrounding one or more hits.
+type Snippet struct {
+	// Offset is the text offset at which the window begins.
+	Offset uint64
+
+	// Text holds the symbols of the window, one byte per symbol.
+	Text string
+
+	// Hits lists the hits within the window, in order of offset.
+	Hits []SnippetHit
+}
+
+// Snippets renders keyword-in-context windows for a list of hits, such as the
+// offsets returned by Search, each of which covers length symbols of a text
+// of bytes.  The snippets are returned in order of offset.
+func Snippets(text *Text, hits []uint64, length uint64, opts SnippetOptions) ([]Snippet, error) {
+	if opts.WholeLines && opts.LineIndex == nil {
+		return nil, errors.New("Snippets: WholeLines requires a LineIndex")
+	}
+
+	sorted := make([]uint64, len(hits))
+	copy(sorted, hits)
+	sort.Sort(byU64(sorted))
+
+	var snippets []Snippet
+	var ends []uint64
+	for _, offset := range sorted {
+		start, end, err := snippetWindow(text, offset, length, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		hit := SnippetHit{Offset: offset}
+		if opts.LineIndex != nil {
+			hit.Line, hit.Column, err = opts.LineIndex.OffsetToLineCol(offset)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if n := len(snippets); opts.Merge && n > 0 && start <= ends[n-1] {
+			if end > ends[n-1] {
+				ends[n-1] = end
+			}
+			snippets[n-1].Hits = append(snippets[n-1].Hits, hit)
+			continue
+		}
+		snippets = append(snippets, Snippet{Offset: start, Hits: []SnippetHit{hit}})
+		ends = append(ends, end)
+	}
+
+	for i := range snippets {
+		buf := make([]byte, 0, ends[i]-snippets[i].Offset)
+		iter := text.Iterate(snippets[i].Offset, ends[i])
+		for iter.Next() {
+			buf = append(buf, byte(iter.Symbol()))
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+		snippets[i].Text = string(buf)
+	}
+	return snippets, nil
+}
+
+// snippetWindow returns the half-open range of text offsets to show around
+// one hit.
+func snippetWindow(text *Text, offset uint64, length uint64, opts SnippetOptions) (uint64, uint64, error) {
+	n := text.Len()
+	if offset > n || length > n-offset {
+		return 0, 0, errors.New("Snippets: hit extends past the end of the text")
+	}
+
+	if !opts.WholeLines {
+		start := uint64(0)
+		if offset > opts.Before {
+			start = offset - opts.Before
+		}
+		end := n
+		if n-offset-length > opts.After {
+			end = offset + length + opts.After
+		}
+		return start, end, nil
+	}
+
+	li := opts.LineIndex
+	last := offset
+	if length > 0 {
+		last = offset + length - 1
+	}
+	firstLine, _, err := li.OffsetToLineCol(offset)
+	if err != nil {
+		return 0, 0, err
+	}
+	lastLine, _, err := li.OffsetToLineCol(last)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if firstLine > opts.Before {
+		firstLine -= opts.Before
+	} else {
+		firstLine = 1
+	}
+	if li.NumLines()-lastLine > opts.After {
+		lastLine += opts.After
+	} else {
+		lastLine = li.NumLines()
+	}
+
+	start, err := li.LineStart(firstLine)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := li.LineEnd(lastLine)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
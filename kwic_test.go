@@ -0,0 +1,133 @@
+package suffixarray
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSnippets(t *testing.T) {
+	const input = "the cat sat\non the mat\nwith a cat\nand a hat"
+
+	type testrow struct {
+		Name     string
+		Hits     []uint64
+		Length   uint64
+		Opts     SnippetOptions
+		Expected string
+	}
+
+	data := []testrow{
+		{
+			Name:     "symbols",
+			Hits:     []uint64{30, 4},
+			Length:   3,
+			Opts:     SnippetOptions{Before: 2, After: 2},
+			Expected: `[{2 "e cat s" [{4 0 0}]} {28 "a cat\na" [{30 0 0}]}]`,
+		},
+		{
+			Name:     "clamped",
+			Hits:     []uint64{0, 40},
+			Length:   3,
+			Opts:     SnippetOptions{Before: 5, After: 5},
+			Expected: `[{0 "the cat " [{0 0 0}]} {35 "nd a hat" [{40 0 0}]}]`,
+		},
+		{
+			Name:     "unmerged",
+			Hits:     []uint64{4, 8},
+			Length:   3,
+			Opts:     SnippetOptions{Before: 1, After: 1},
+			Expected: `[{3 " cat " [{4 0 0}]} {7 " sat\n" [{8 0 0}]}]`,
+		},
+		{
+			Name:     "merged",
+			Hits:     []uint64{8, 4},
+			Length:   3,
+			Opts:     SnippetOptions{Before: 1, After: 1, Merge: true},
+			Expected: `[{3 " cat sat\n" [{4 0 0} {8 0 0}]}]`,
+		},
+		{
+			Name:     "lines",
+			Hits:     []uint64{4, 30},
+			Length:   3,
+			Opts:     SnippetOptions{WholeLines: true},
+			Expected: `[{0 "the cat sat" [{4 1 5}]} {23 "with a cat" [{30 3 8}]}]`,
+		},
+		{
+			Name:     "context lines",
+			Hits:     []uint64{19},
+			Length:   3,
+			Opts:     SnippetOptions{Before: 1, After: 5, WholeLines: true},
+			Expected: `[{0 "the cat sat\non the mat\nwith a cat\nand a hat" [{19 2 8}]}]`,
+		},
+		{
+			Name:     "merged lines",
+			Hits:     []uint64{4, 8, 30},
+			Length:   3,
+			Opts:     SnippetOptions{WholeLines: true, Merge: true},
+			Expected: `[{0 "the cat sat" [{4 1 5} {8 1 9}]} {23 "with a cat" [{30 3 8}]}]`,
+		},
+		{
+			Name:     "spanning lines",
+			Hits:     []uint64{8},
+			Length:   7,
+			Opts:     SnippetOptions{WholeLines: true},
+			Expected: `[{0 "the cat sat\non the mat" [{8 1 9}]}]`,
+		},
+		{
+			Name:     "empty",
+			Hits:     nil,
+			Length:   3,
+			Opts:     SnippetOptions{Before: 1, After: 1},
+			Expected: `[]`,
+		},
+	}
+
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		text := NewTextFromString(input, opts...)
+		li, err := BuildLineIndex(text, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildLineIndex: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for _, row := range data {
+			snippetOpts := row.Opts
+			if snippetOpts.WholeLines {
+				snippetOpts.LineIndex = li
+			}
+			snippets, err := Snippets(text, row.Hits, row.Length, snippetOpts)
+			if err != nil {
+				t.Errorf("[%s] Snippets %s: error: %v", cfg.Name, row.Name, err)
+				continue
+			}
+			actual := "["
+			for i, s := range snippets {
+				if i > 0 {
+					actual += " "
+				}
+				actual += fmt.Sprintf("{%d %q %v}", s.Offset, s.Text, s.Hits)
+			}
+			actual += "]"
+			if actual != row.Expected {
+				t.Errorf("[%s] Snippets %s: expected %s, got %s", cfg.Name, row.Name, row.Expected, actual)
+			}
+		}
+
+		if _, err := Snippets(text, []uint64{0}, 3, SnippetOptions{WholeLines: true}); err == nil {
+			t.Errorf("[%s] Snippets without LineIndex: expected error", cfg.Name)
+		}
+		if _, err := Snippets(text, []uint64{40}, 10, SnippetOptions{}); err == nil {
+			t.Errorf("[%s] Snippets past end: expected error", cfg.Name)
+		}
+
+		if err := li.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+		if err := text.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
@@ -14,12 +14,12 @@ import (
 // Index n≥1 is lcp(SA[n-1], SA[n]); index 0 is undefined.
 //
 type LCPArray struct {
-	ba bigarray.BigArray
+	ba Storage
 }
 
 // LCPIterator iterates over an LCP array.
 type LCPIterator struct {
-	impl bigarray.Iterator
+	impl StorageIterator
 }
 
 // NewLCPArray constructs an LCP array.
@@ -28,7 +28,7 @@ func NewLCPArray(opts ...Option) (*LCPArray, error) {
 		opts,
 		BytesPerValue(8))
 
-	ba, err := makeBigArray(opts)
+	ba, err := makeStorage(opts)
 	if err != nil {
 		return nil, err
 	}
@@ -60,7 +60,7 @@ func (lcp *LCPArray) Iterate(i, j uint64) *LCPIterator {
 // CopyFrom copies all values from a source LCP array to this array. The two
 // arrays must have the same Len().
 func (lcp *LCPArray) CopyFrom(src *LCPArray) error {
-	return lcp.ba.CopyFrom(src.ba)
+	return copyStorage(lcp.ba, src.ba)
 }
 
 // Truncate trims the LCPArray to the given length.
@@ -80,7 +80,7 @@ func (lcp *LCPArray) Debug() string {
 	var buf bytes.Buffer
 	buf.WriteByte('[')
 	buf.WriteByte('.')
-	err := bigarray.ForEach(lcp.ba, func(index uint64, height uint64) error {
+	err := forEachValue(lcp.ba, func(index uint64, height uint64) error {
 		if index == 0 {
 			return nil
 		}
@@ -176,7 +176,7 @@ func BuildLCPArray(text *Text, sa *SuffixArray, opts ...Option) (*LCPArray, erro
 	//
 	// RA instead maps each offset in the text to the corresponding index
 	// in SA for the suffix starting at that offset.
-	rankArray, err := makeBigArray(rankOpts)
+	rankArray, err := makeStorage(rankOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -204,7 +204,7 @@ func BuildLCPArray(text *Text, sa *SuffixArray, opts ...Option) (*LCPArray, erro
 	// Algorithm below given by [1]
 
 	h := uint64(0)
-	err = bigarray.ForEach(rankArray, func(indexI, rank uint64) error {
+	err = forEachValue(rankArray, func(indexI, rank uint64) error {
 		if rank <= 1 {
 			return nil
 		}
@@ -274,28 +274,33 @@ func BuildLCPArray(text *Text, sa *SuffixArray, opts ...Option) (*LCPArray, erro
 //    [12] nana$           9   2
 //    [13] nana.banana$    2   4
 //
-// Each value in the LCP-LR array is the lcp(A_i, A_j) for a single binary
-// search comparison: the 0th index contains the first comparison, i=0 j=13;
-// the 1st and 2nd indices contain the two permissible second comparisons,
-// i=0 j=5 (left) and i=7 j=13 (right); the 3rd, 4th, 5th, and 6th indices
-// contain the permissible third comparisons, i=0 j=1 (LL), i=3 j=5 (LR),
-// i=7 j=9 (RL), and i=11 j=13 (RR), and so on for all O(log N) string
-// comparisons.
+// Each value in the LCP-LR array is the lcp(A_i, A_j) for a pair of suffixes
+// which bound a range of the binary search, where A_14 is an imaginary suffix
+// which sorts after all the others and shares no prefix with them: the 0th
+// index contains the whole range, i=0 j=14; the 1st and 2nd indices contain
+// its two halves, i=0 j=7 (left) and i=7 j=14 (right), which share the
+// midpoint; the 3rd, 4th, 5th, and 6th indices contain i=0 j=3 (LL), i=3 j=7
+// (LR), i=7 j=10 (RL), and i=10 j=14 (RR), and so on for all O(log N) levels,
+// down to the ranges of a single step.
 //
 // Note that once we have the LCP array, with its pairwise calculations of
 // LCP[n] = lcp(n - 1, n), we can compute lcp(lo, hi) as
 //
-// lcp(lo, hi) = min(lcp(lo, mid - 1), lcp(mid - 1, mid), lcp(mid, mid + 1), lcp(mid + 1, hi))
-//             = min(LCP-LR[2*n+1],    LCP[mid],          LCP[mid+1],        LCP-LR[2*n+2])
+// lcp(lo, hi) = min(lcp(lo, mid),  lcp(mid, hi))
+//             = min(LCP-LR[2*n+1], LCP-LR[2*n+2])
 //
 //      i  LCP-LR    computation
-//     [0] 0         lcp( 0, 13) -> min(LCP-LR[1], LCP[ 6], LCP[ 7], LCP-LR[2]) -> min(0, 3, 5, 0)
-//     [1] 0         lcp( 0,  5) -> min(LCP-LR[3], LCP[ 2], LCP[ 3], LCP-LR[4]) -> min(0, 0, 1, 1)
-//     [2] 0         lcp( 7, 13) -> min(LCP-LR[5], LCP[10], LCP[11], LCP-LR[6]) -> min(0, 0, 2, 2)
-//     [3] 0         lcp( 0,  1) -> LCP[1]                                      -> 0
-//     [4] 1         lcp( 3,  5) -> min(LCP[ 4], LCP[ 5])                       -> min(1, 3)
-//     [5] 0         lcp( 7,  9) -> min(LCP[ 8], LCP[ 9])                       -> min(0, 6)
-//     [6] 2         lcp(11, 13) -> min(LCP[12], LCP[13])                       -> min(2, 4)
+//     [0] 0         lcp( 0, 14) -> min(LCP-LR[ 1], LCP-LR[ 2]) -> min(0, 0)
+//     [1] 0         lcp( 0,  7) -> min(LCP-LR[ 3], LCP-LR[ 4]) -> min(0, 1)
+//     [2] 0         lcp( 7, 14) -> min(LCP-LR[ 5], LCP-LR[ 6]) -> min(0, 0)
+//     [3] 0         lcp( 0,  3) -> min(LCP-LR[ 7], LCP-LR[ 8]) -> min(0, 0)
+//     [4] 1         lcp( 3,  7) -> min(LCP-LR[ 9], LCP-LR[10]) -> min(1, 3)
+//     [5] 0         lcp( 7, 10) -> min(LCP-LR[11], LCP-LR[12]) -> min(0, 0)
+//     [6] 0         lcp(10, 14) -> min(LCP-LR[13], LCP-LR[14]) -> min(2, 0)
+//     [7] 0         lcp( 0,  1) -> LCP[1]
+//     ...
+//    [29] 4         lcp(12, 13) -> LCP[13]
+//    [30] 0         lcp(13, 14) -> 0
 //
 // ----------------------------------------------------------------------------
 //
@@ -314,26 +319,48 @@ func BuildLCPArray(text *Text, sa *SuffixArray, opts ...Option) (*LCPArray, erro
 //    H -> the suffix starting at offset SA[hi]
 //    P -> the phrase being searched for
 //
-// We need to compare M to P, so that we know whether the next step should be
-// (lo, mid-1) or (mid+1, hi).  However, the suffix array is sorted, so as the
-// search drills in toward the target suffix(es) we will increasingly encounter
-// long common prefixes between M and P.
+// The search keeps L < P < H, along with l = lcp(L, P) and h = lcp(P, H)
+// from the comparisons which found L and H.  We need to compare M to P, so
+// that we know whether the next step should be (lo, mid) or (mid, hi).
+// However, the suffix array is sorted, so as the search drills in toward the
+// target suffix(es) we will increasingly encounter long common prefixes.
 //
-// If we know lcp(L, R), however, then we also know that all suffices *between*
-// L and R, *including* M, have that many identical bytes at the start of each
-// suffix.  And we discovered L and R in the first place because L ≤ P ≤ R.
+// Suppose that l ≥ h; the other case is the mirror image, using lcp(M, H).
+// The LCP-LR array gives us lcp(L, M), and:
 //
-// Therefore, P also shares at least that many bytes with M.  We have no need
-// to check the first lcp(L, R) bytes of P vs M, because we can be certain that
-// those two substrings are identical.
+//  - if lcp(L, M) > l, then M agrees with L where P first differs from L,
+//    so M < P, and the next step is (mid, hi) without reading M at all;
 //
-// Reference:
+//  - if lcp(L, M) < l, then M differs from L where P still agrees with L,
+//    so P < M, and the next step is (lo, mid) with h = lcp(L, M);
+//
+//  - otherwise, P shares at least l bytes with M, and the comparison of P
+//    against M can skip them.
+//
+// A comparison only examines the bytes of P past max(l, h), which it then
+// raises, so the search takes O(m + log n) time.  Note that P need not share
+// lcp(L, H) bytes with M, as L and H may agree further than P does.
+//
+// References:
 //
 //  [2] Answer to Stack Overflow question “How do we Construct LCP-LR array from LCP array?”,
 //      Abhijeet Ashok Muneshwar.
 //      https://stackoverflow.com/a/38588642/4848155
 //
+//  [3] “Suffix arrays: a new method for on-line string searches”,
+//      Udi Manber and Gene Myers.
+//      https://doi.org/10.1137/0222058
+//
 func BuildLCPLRArray(lcp *LCPArray, opts ...Option) (bigarray.BigArray, error) {
+	lcplr, err := buildLCPLRArray(lcp, opts)
+	if err != nil {
+		return nil, err
+	}
+	return storageToBigArray(lcplr), nil
+}
+
+// buildLCPLRArray is like BuildLCPLRArray, but returns the array as a Storage.
+func buildLCPLRArray(lcp *LCPArray, opts []Option) (Storage, error) {
 	// Round lcp.Len() up to a power of 2
 	n := uint64(math.Exp2(math.Ceil(math.Log2(float64(lcp.Len())))))
 
@@ -342,7 +369,7 @@ func BuildLCPLRArray(lcp *LCPArray, opts ...Option) (bigarray.BigArray, error) {
 		NumValues(2*n+1),
 		BytesPerValue(8))
 
-	lcplr, err := makeBigArray(opts)
+	lcplr, err := makeStorage(opts)
 	if err != nil {
 		return nil, err
 	}
@@ -363,7 +390,7 @@ func BuildLCPLRArray(lcp *LCPArray, opts ...Option) (bigarray.BigArray, error) {
 		return nil, err
 	}
 
-	_, err = buildLCPLR(lcplr, lcp, 0, 0, lcp.Len()-1)
+	_, err = buildLCPLR(lcplr, lcp, 0, 0, lcp.Len())
 	if err != nil {
 		lcplr.Close()
 		return nil, err
@@ -391,86 +418,38 @@ func BuildLCPLRArray(lcp *LCPArray, opts ...Option) (bigarray.BigArray, error) {
 	return lcplr, nil
 }
 
-func buildLCPLR(lcplr bigarray.BigArray, lcp *LCPArray, index, lo, hi uint64) (uint64, error) {
+func buildLCPLR(lcplr Storage, lcp *LCPArray, index, lo, hi uint64) (uint64, error) {
 	if lo >= hi {
 		panic(fmt.Errorf("BUG: %d >= %d", lo, hi))
 	}
 
-	var err error
-	var h0 uint64
-	h1 := ^uint64(0)
-	h2 := ^uint64(0)
-	h3 := ^uint64(0)
+	var h uint64
+	if hi-lo > 1 {
+		mid := lo + (hi-lo)/2
 
-	delta := (hi - lo)
-	if delta > 3 {
-		mid := lo + delta/2
-		x := 2*index + 1
-		y := 2*index + 2
-
-		h0, err = buildLCPLR(lcplr, lcp, x, lo, mid-1)
-		if err != nil {
-			return 0, err
-		}
-
-		h1, err = lcp.HeightAt(mid)
+		h0, err := buildLCPLR(lcplr, lcp, 2*index+1, lo, mid)
 		if err != nil {
 			return 0, err
 		}
 
-		h2, err = lcp.HeightAt(mid + 1)
+		h1, err := buildLCPLR(lcplr, lcp, 2*index+2, mid, hi)
 		if err != nil {
 			return 0, err
 		}
 
-		h3, err = buildLCPLR(lcplr, lcp, y, mid+1, hi)
-		if err != nil {
-			return 0, err
+		h = h0
+		if h > h1 {
+			h = h1
 		}
-	} else if delta == 3 {
-		h0, err = lcp.HeightAt(lo + 1)
+	} else if hi < lcp.Len() {
+		var err error
+		h, err = lcp.HeightAt(hi)
 		if err != nil {
 			return 0, err
 		}
-
-		h1, err = lcp.HeightAt(lo + 2)
-		if err != nil {
-			return 0, err
-		}
-
-		h2, err = lcp.HeightAt(lo + 3)
-		if err != nil {
-			return 0, err
-		}
-	} else if delta == 2 {
-		h0, err = lcp.HeightAt(lo + 1)
-		if err != nil {
-			return 0, err
-		}
-
-		h1, err = lcp.HeightAt(lo + 2)
-		if err != nil {
-			return 0, err
-		}
-	} else {
-		h0, err = lcp.HeightAt(lo + 1)
-		if err != nil {
-			return 0, err
-		}
-	}
-
-	h := h0
-	if h > h1 {
-		h = h1
-	}
-	if h > h2 {
-		h = h2
-	}
-	if h > h3 {
-		h = h3
 	}
 
-	err = lcplr.SetValueAt(index, h)
+	err := lcplr.SetValueAt(index, h)
 	if err != nil {
 		return 0, err
 	}
@@ -74,47 +74,24 @@ func (builder *naiveLCPLRBuilder) recurse(index, lo, hi uint64) uint64 {
 		panic(fmt.Errorf("BUG: %d >= %d", lo, hi))
 	}
 
-	var h0 uint64
-	h1 := ^uint64(0)
-	h2 := ^uint64(0)
-	h3 := ^uint64(0)
-
-	delta := (hi - lo)
-
-	if delta > 3 {
-		mid := lo + delta/2
-		x := 2*index + 1
-		y := 2*index + 2
-
-		h0 = builder.recurse(x, lo, mid-1)
-		h1 = builder.computeLCP(mid-1, mid)
-		h2 = builder.computeLCP(mid, mid+1)
-		h3 = builder.recurse(y, mid+1, hi)
-	} else if delta == 3 {
-		h0 = builder.computeLCP(lo, lo+1)
-		h1 = builder.computeLCP(lo+1, lo+2)
-		h2 = builder.computeLCP(lo+2, lo+3)
-	} else if delta == 2 {
-		h0 = builder.computeLCP(lo, lo+1)
-		h1 = builder.computeLCP(lo+1, lo+2)
-	} else {
-		h0 = builder.computeLCP(lo, lo+1)
+	h := ^uint64(0)
+	if hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		h0 := builder.recurse(2*index+1, lo, mid)
+		h1 := builder.recurse(2*index+2, mid, hi)
+
+		h = h0
+		if h > h1 {
+			h = h1
+		}
 	}
 
-	h := h0
-	if h > h1 {
-		h = h1
+	hh := uint64(0)
+	if hi < builder.sa.Len() {
+		hh = builder.computeLCP(lo, hi)
 	}
-	if h > h2 {
-		h = h2
-	}
-	if h > h3 {
-		h = h3
-	}
-
-	hh := builder.computeLCP(lo, hi)
-	if h != hh {
-		panic(fmt.Errorf("BUG: h0=%d h1=%d h2=%d h3=%d h=%d hh=%d", h0, h1, h2, h3, h, hh))
+	if h != ^uint64(0) && h != hh {
+		panic(fmt.Errorf("BUG: h=%d hh=%d", h, hh))
 	}
 
 	builder.out[index] = hh
@@ -137,7 +114,7 @@ func NaiveBuildLCPLRArray(text *Text, sa *SuffixArray) ([]uint64, string) {
 		sa:   sa,
 		out:  lcplr,
 	}
-	builder.recurse(0, 0, sa.Len()-1)
+	builder.recurse(0, 0, sa.Len())
 
 	finalLen := uint64(len(lcplr))
 	for finalLen > 0 {
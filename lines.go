@@ -0,0 +1,245 @@
+package suffixarray
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// LineIndex records the offset at which each line of a Text begins, so that
+// text offsets can be converted to line and column numbers and back.
+//
+// Every newline symbol ('\n') ends one line and begins the next, so a text
+// ending in a newline has an empty last line.  Line and column numbers are
+// 1-based, as is customary for display.
+//
+// The line starts are the positions of the newlines plus one, preceded by 0,
+// so looking up a line's start is a select on the newlines and mapping an
+// offset to its line is a rank, done by binary search in O(log n).
+//
+type LineIndex struct {
+	starts Storage
+	length uint64
+}
+
+// lineIndexMagic begins the serialized form of a LineIndex.
+const lineIndexMagic = "LIDX"
+
+// BuildLineIndex scans a Text for newlines and constructs its LineIndex.
+func BuildLineIndex(text *Text, opts ...Option) (*LineIndex, error) {
+	starts, err := newLineStartsBuilder(text.Len(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer starts.Close()
+
+	if err := starts.Append(0); err != nil {
+		return nil, err
+	}
+	err = text.ForEach(func(index uint64, symbol uint64) error {
+		if symbol == '\n' {
+			return starts.Append(index + 1)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ba, err := starts.Finish()
+	if err != nil {
+		return nil, err
+	}
+	return &LineIndex{starts: ba, length: text.Len()}, nil
+}
+
+// ReadLineIndex reads a LineIndex in the form written by WriteTo.
+func ReadLineIndex(r io.Reader, opts ...Option) (*LineIndex, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(lineIndexMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if string(magic) != lineIndexMagic {
+		return nil, errors.New("ReadLineIndex: not a serialized LineIndex")
+	}
+
+	length, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	numLines, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if numLines < 1 || numLines-1 > length {
+		return nil, fmt.Errorf("ReadLineIndex: %d lines is impossible for text of length %d", numLines, length)
+	}
+
+	starts, err := newLineStartsBuilder(length, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer starts.Close()
+
+	if err := starts.Append(0); err != nil {
+		return nil, err
+	}
+	start := uint64(0)
+	for i := uint64(1); i < numLines; i++ {
+		delta, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if delta < 1 || delta > length-start {
+			return nil, fmt.Errorf("ReadLineIndex: line %d has invalid length %d", i, delta-1)
+		}
+		start += delta
+		if err := starts.Append(start); err != nil {
+			return nil, err
+		}
+	}
+
+	ba, err := starts.Finish()
+	if err != nil {
+		return nil, err
+	}
+	return &LineIndex{starts: ba, length: length}, nil
+}
+
+func newLineStartsBuilder(length uint64, opts []Option) (*arrayBuilder, error) {
+	// A MaxValue of 0 is taken as unset, so an empty text needs a bound.
+	maxValue := length
+	if maxValue == 0 {
+		maxValue = 1
+	}
+	return newArrayBuilder(extendOptions(
+		opts,
+		MaxValue(maxValue)))
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// WriteTo writes the LineIndex to w in a compact form which ReadLineIndex
+// can read back, so that it can be persisted alongside the Index of the same
+// text.
+func (li *LineIndex) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var total int64
+	var scratch [binary.MaxVarintLen64]byte
+	put := func(value uint64) error {
+		n, err := bw.Write(scratch[:binary.PutUvarint(scratch[:], value)])
+		total += int64(n)
+		return err
+	}
+
+	n, err := bw.WriteString(lineIndexMagic)
+	total += int64(n)
+	if err != nil {
+		return total, err
+	}
+	if err := put(li.length); err != nil {
+		return total, err
+	}
+	if err := put(li.NumLines()); err != nil {
+		return total, err
+	}
+
+	// Each line start is stored as its distance from the previous one.
+	var prev uint64
+	err = forEachValue(li.starts, func(index uint64, start uint64) error {
+		if index == 0 {
+			return nil
+		}
+		err := put(start - prev)
+		prev = start
+		return err
+	})
+	if err != nil {
+		return total, err
+	}
+	return total, bw.Flush()
+}
+
+// Len returns the length of the indexed text.
+func (li *LineIndex) Len() uint64 { return li.length }
+
+// NumLines returns the number of lines in the text.
+func (li *LineIndex) NumLines() uint64 { return li.starts.Len() }
+
+// LineStart returns the offset of the first symbol of the given line.
+func (li *LineIndex) LineStart(line uint64) (uint64, error) {
+	if line < 1 || line > li.NumLines() {
+		return 0, fmt.Errorf("LineIndex.LineStart: line %d is out of range [1, %d]", line, li.NumLines())
+	}
+	return li.starts.ValueAt(line - 1)
+}
+
+// LineEnd returns the offset just past the last symbol of the given line,
+// not counting its newline.
+func (li *LineIndex) LineEnd(line uint64) (uint64, error) {
+	if line < 1 || line > li.NumLines() {
+		return 0, fmt.Errorf("LineIndex.LineEnd: line %d is out of range [1, %d]", line, li.NumLines())
+	}
+	if line == li.NumLines() {
+		return li.length, nil
+	}
+	next, err := li.starts.ValueAt(line)
+	if err != nil {
+		return 0, err
+	}
+	return next - 1, nil
+}
+
+// OffsetToLineCol returns the line and column numbers of the given text
+// offset.  The offset may equal the length of the text, which is located just
+// past the last symbol.
+func (li *LineIndex) OffsetToLineCol(offset uint64) (line uint64, column uint64, err error) {
+	if offset > li.length {
+		return 0, 0, fmt.Errorf("LineIndex.OffsetToLineCol: offset %d is out of range for text of length %d", offset, li.length)
+	}
+
+	// Find the first line which starts after offset.
+	next, err := searchIndices(0, li.starts.Len(), func(index uint64) (bool, error) {
+		start, err := li.starts.ValueAt(index)
+		return start > offset, err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start, err := li.starts.ValueAt(next - 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return next, offset - start + 1, nil
+}
+
+// LineColToOffset returns the text offset of the given line and column
+// numbers.  The column may be one past the end of the line, which is the
+// offset of its newline.
+func (li *LineIndex) LineColToOffset(line uint64, column uint64) (uint64, error) {
+	start, err := li.LineStart(line)
+	if err != nil {
+		return 0, err
+	}
+	end, err := li.LineEnd(line)
+	if err != nil {
+		return 0, err
+	}
+	if column < 1 || column-1 > end-start {
+		return 0, fmt.Errorf("LineIndex.LineColToOffset: column %d is out of range [1, %d] for line %d", column, end-start+1, line)
+	}
+	return start + column - 1, nil
+}
+
+// Close frees the resources used by the LineIndex.
+func (li *LineIndex) Close() error { return li.starts.Close() }
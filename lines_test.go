@@ -0,0 +1,103 @@
+package suffixarray
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLineIndex(t *testing.T) {
+	inputs := []string{
+		"",
+		"one line",
+		"a\nbb\n\nccc\n",
+		sampleText,
+	}
+
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		for _, input := range inputs {
+			text := NewTextFromString(input, opts...)
+			li, err := BuildLineIndex(text, opts...)
+			if err != nil {
+				t.Errorf("[%s] BuildLineIndex %q: error: %v", cfg.Name, input, err)
+				continue
+			}
+
+			lines := strings.Split(input, "\n")
+			if li.NumLines() != uint64(len(lines)) {
+				t.Errorf("[%s] NumLines %q: expected %d, got %d", cfg.Name, input, len(lines), li.NumLines())
+			}
+
+			start := uint64(0)
+			for i, line := range lines {
+				lineNum := uint64(i + 1)
+				end := start + uint64(len(line))
+				if actual, err := li.LineStart(lineNum); err != nil || actual != start {
+					t.Errorf("[%s] LineStart %q %d: expected %d, got %d, %v", cfg.Name, input, lineNum, start, actual, err)
+				}
+				if actual, err := li.LineEnd(lineNum); err != nil || actual != end {
+					t.Errorf("[%s] LineEnd %q %d: expected %d, got %d, %v", cfg.Name, input, lineNum, end, actual, err)
+				}
+				for offset := start; offset <= end; offset++ {
+					l, c, err := li.OffsetToLineCol(offset)
+					if err != nil || l != lineNum || c != offset-start+1 {
+						t.Errorf("[%s] OffsetToLineCol %q %d: expected %d:%d, got %d:%d, %v", cfg.Name, input, offset, lineNum, offset-start+1, l, c, err)
+					}
+					if actual, err := li.LineColToOffset(lineNum, offset-start+1); err != nil || actual != offset {
+						t.Errorf("[%s] LineColToOffset %q %d:%d: expected %d, got %d, %v", cfg.Name, input, lineNum, offset-start+1, offset, actual, err)
+					}
+				}
+				if _, err := li.LineColToOffset(lineNum, end-start+2); err == nil {
+					t.Errorf("[%s] LineColToOffset %q %d past end of line: expected error", cfg.Name, input, lineNum)
+				}
+				start = end + 1
+			}
+
+			if _, err := li.LineStart(0); err == nil {
+				t.Errorf("[%s] LineStart %q 0: expected error", cfg.Name, input)
+			}
+			if _, err := li.LineEnd(li.NumLines() + 1); err == nil {
+				t.Errorf("[%s] LineEnd %q past end: expected error", cfg.Name, input)
+			}
+			if _, _, err := li.OffsetToLineCol(text.Len() + 1); err == nil {
+				t.Errorf("[%s] OffsetToLineCol %q past end: expected error", cfg.Name, input)
+			}
+
+			var buf bytes.Buffer
+			if n, err := li.WriteTo(&buf); err != nil || n != int64(buf.Len()) {
+				t.Errorf("[%s] WriteTo %q: expected %d bytes, got %d, %v", cfg.Name, input, buf.Len(), n, err)
+			}
+			serialized := buf.Bytes()
+			li2, err := ReadLineIndex(bytes.NewReader(serialized), opts...)
+			if err != nil {
+				t.Errorf("[%s] ReadLineIndex %q: error: %v", cfg.Name, input, err)
+			} else {
+				if li2.Len() != li.Len() || li2.NumLines() != li.NumLines() {
+					t.Errorf("[%s] ReadLineIndex %q: expected %d symbols and %d lines, got %d and %d", cfg.Name, input, li.Len(), li.NumLines(), li2.Len(), li2.NumLines())
+				}
+				for line := uint64(1); line <= li.NumLines(); line++ {
+					expected, _ := li.LineStart(line)
+					if actual, err := li2.LineStart(line); err != nil || actual != expected {
+						t.Errorf("[%s] ReadLineIndex %q: line %d: expected start %d, got %d, %v", cfg.Name, input, line, expected, actual, err)
+					}
+				}
+				if err := li2.Close(); err != nil {
+					t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+				}
+			}
+			if _, err := ReadLineIndex(bytes.NewReader(serialized[:len(serialized)-1]), opts...); err == nil {
+				t.Errorf("[%s] ReadLineIndex %q truncated: expected error", cfg.Name, input)
+			}
+
+			if err := li.Close(); err != nil {
+				t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+			}
+			if err := text.Close(); err != nil {
+				t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+			}
+		}
+	}
+}
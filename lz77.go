@@ -0,0 +1,174 @@
+package suffixarray
+
+// LZ77Factor is a single phrase of an LZ77 parse.
+//
+// A factor either copies Length symbols from the earlier text offset Source,
+// or is a literal occurrence of Symbol which did not appear earlier in the
+// text.  Source ranges may overlap the factor itself, as in "aaaa" → 'a' +
+// (copy 3 from offset 0).
+//
+type LZ77Factor struct {
+	// Offset is the text offset at which this factor begins.
+	Offset uint64
+
+	// Length is the number of symbols covered by this factor.  It is
+	// always 1 for literals.
+	Length uint64
+
+	// Source is the earlier text offset which this factor copies from.
+	// It is undefined for literals.
+	Source uint64
+
+	// Symbol is the symbol emitted by a literal factor.  It is undefined
+	// for copy factors.
+	Symbol uint64
+
+	// Literal is true iff this factor is a literal symbol.
+	Literal bool
+}
+
+// LZ77Factorize computes the greedy LZ77 parse of the given Text, calling fn
+// once for each factor in text order.  If fn returns an error, the parse is
+// aborted and the error is returned.
+//
+// The parse is computed in O(n) time from the suffix array alone, using the
+// KKP3 algorithm: for each text offset i we find PSV[i] and NSV[i], the
+// nearest suffixes on either side of i in the suffix array that start
+// *before* i in the text.  The longest previous factor starting at i is
+// necessarily shared with one of those two suffixes, so each factor costs
+// only two direct comparisons against the text.
+//
+// PSV and NSV are each the size of the text and are stored in big arrays
+// constructed from opts, so very large texts can be factorized on disk.
+//
+// Reference:
+//
+//  [1] “Linear Time Lempel-Ziv Factorization: Simple, Fast, Small”,
+//      Juha Kärkkäinen, Dominik Kempa, and Simon J. Puglisi.
+//      https://doi.org/10.1007/978-3-642-38905-4_19
+//
+func LZ77Factorize(text *Text, sa *SuffixArray, fn func(LZ77Factor) error, opts ...Option) error {
+	n := text.Len()
+	if n == 0 {
+		return nil
+	}
+
+	opts = extendOptions(
+		opts,
+		NumValues(n),
+		BytesPerValue(8),
+		WithFile(nil))
+
+	psv, err := makeStorage(opts)
+	if err != nil {
+		return err
+	}
+	defer psv.Close()
+
+	nsv, err := makeStorage(opts)
+	if err != nil {
+		return err
+	}
+	defer nsv.Close()
+
+	iter := nsv.Iterate(0, nsv.Len())
+	for iter.Next() {
+		iter.SetValue(placeholder)
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	// The PSV array doubles as the stack for computing NSV: the entries
+	// still awaiting an NSV form a chain of increasing text offsets,
+	// linked together through their PSV values.
+	top := placeholder
+	err = sa.ForEach(func(index uint64, pos uint64) error {
+		if index == 0 {
+			return nil
+		}
+		for top != placeholder && top > pos {
+			if err := nsv.SetValueAt(top, pos); err != nil {
+				return err
+			}
+			next, err := psv.ValueAt(top)
+			if err != nil {
+				return err
+			}
+			top = next
+		}
+		if err := psv.SetValueAt(pos, top); err != nil {
+			return err
+		}
+		top = pos
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	i := uint64(0)
+	for i < n {
+		factor, err := lz77FactorAt(text, psv, nsv, i)
+		if err != nil {
+			return err
+		}
+		if err := fn(factor); err != nil {
+			return err
+		}
+		i += factor.Length
+	}
+	return nil
+}
+
+func lz77FactorAt(text *Text, psv, nsv Storage, i uint64) (LZ77Factor, error) {
+	factor := LZ77Factor{Offset: i}
+
+	for _, candidates := range []Storage{psv, nsv} {
+		j, err := candidates.ValueAt(i)
+		if err != nil {
+			return factor, err
+		}
+		if j == placeholder {
+			continue
+		}
+
+		h, err := lz77MatchLength(text, i, j)
+		if err != nil {
+			return factor, err
+		}
+		if h > factor.Length {
+			factor.Length = h
+			factor.Source = j
+		}
+	}
+
+	if factor.Length == 0 {
+		symbol, err := text.SymbolAt(i)
+		if err != nil {
+			return factor, err
+		}
+		factor.Length = 1
+		factor.Symbol = symbol
+		factor.Literal = true
+	}
+	return factor, nil
+}
+
+// lz77MatchLength returns lcp(TEXT[i:], TEXT[j:]) for j < i.
+func lz77MatchLength(text *Text, i, j uint64) (uint64, error) {
+	iterI := text.Iterate(i, text.Len())
+	iterJ := text.Iterate(j, text.Len())
+	h := uint64(0)
+	for iterI.Next() && iterJ.Next() && iterI.Symbol() == iterJ.Symbol() {
+		h++
+	}
+	if err := iterI.Close(); err != nil {
+		iterJ.Close()
+		return 0, err
+	}
+	if err := iterJ.Close(); err != nil {
+		return 0, err
+	}
+	return h, nil
+}
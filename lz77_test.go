@@ -0,0 +1,104 @@
+package suffixarray
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func NaiveLZ77Factorize(text string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	i := 0
+	for i < len(text) {
+		best := 0
+		for j := 0; j < i; j++ {
+			h := 0
+			for i+h < len(text) && text[j+h] == text[i+h] {
+				h++
+			}
+			if h > best {
+				best = h
+			}
+		}
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		if best == 0 {
+			fmt.Fprintf(&buf, "%q", text[i])
+			best = 1
+		} else {
+			fmt.Fprintf(&buf, "%d", best)
+		}
+		i += best
+	}
+	buf.WriteByte(']')
+	return buf.String()
+}
+
+func TestLZ77Factorize(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		for i, input := range []string{
+			"",
+			"a",
+			banana,
+			banana2,
+			cabbage,
+			loremIpsum,
+			abcdefgh,
+			aaaaaaaa,
+			"abababab",
+			sampleText,
+		} {
+			text := NewTextFromString(input, opts...)
+
+			sa, err := BuildSuffixArray(text, opts...)
+			if err != nil {
+				t.Errorf("[%s/%03d] BuildSuffixArray: error: %v", cfg.Name, i, err)
+				continue
+			}
+
+			var buf bytes.Buffer
+			var decoded []byte
+			buf.WriteByte('[')
+			err = LZ77Factorize(text, sa, func(factor LZ77Factor) error {
+				if factor.Offset != uint64(len(decoded)) {
+					return fmt.Errorf("factor at %d, expected %d", factor.Offset, len(decoded))
+				}
+				if factor.Offset > 0 {
+					buf.WriteByte(' ')
+				}
+				if factor.Literal {
+					fmt.Fprintf(&buf, "%q", byte(factor.Symbol))
+					decoded = append(decoded, byte(factor.Symbol))
+					return nil
+				}
+				if factor.Source >= factor.Offset {
+					return fmt.Errorf("factor at %d copies from %d", factor.Offset, factor.Source)
+				}
+				fmt.Fprintf(&buf, "%d", factor.Length)
+				for k := uint64(0); k < factor.Length; k++ {
+					decoded = append(decoded, decoded[factor.Source+k])
+				}
+				return nil
+			}, opts...)
+			buf.WriteByte(']')
+			if err != nil {
+				t.Errorf("[%s/%03d] LZ77Factorize: error: %v", cfg.Name, i, err)
+				continue
+			}
+
+			expected := NaiveLZ77Factorize(input)
+			actual := buf.String()
+			if expected != actual {
+				t.Errorf("[%s/%03d] LZ77Factorize: expected %s, got %s", cfg.Name, i, expected, actual)
+			}
+			if string(decoded) != input {
+				t.Errorf("[%s/%03d] LZ77Factorize: decoded %q, expected %q", cfg.Name, i, decoded, input)
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package suffixarray
+
+import (
+	"bufio"
+	"io"
+)
+
+// MatchingStatistic describes the longest match in the indexed text for one
+// position of a query.
+type MatchingStatistic struct {
+	// Offset is the position in the query.
+	Offset uint64
+
+	// Length is the length of the longest prefix of query[Offset:] which
+	// occurs in the indexed text.
+	Length uint64
+
+	// Position is the text offset of one occurrence of that prefix.  It is
+	// meaningless if Length is 0.
+	Position uint64
+}
+
+// MatchingStatistics reads a query of bytes and calls fn with the matching
+// statistic for each position of the query, in order.  If fn returns an
+// error, MatchingStatistics stops and returns that error.
+//
+// The computation keeps the suffix array range of the current match, as a
+// suffix tree search would keep its current node.  Moving from one query
+// position to the next drops the first symbol of the match, which is what a
+// suffix link does in a suffix tree; here it is emulated by stepping from an
+// occurrence of the match at text offset p to the suffix at p+1, via the
+// inverse suffix array, and then widening that single suffix into a full
+// range, out to the nearest LCP values on either side which are below the new
+// match length.  Those are found by range minimum queries over the LCP array,
+// first doubling the distance until a query finds one and then bisecting, so
+// widening to a range of w suffixes costs O(log w) queries.  The match is then
+// extended one symbol at a time by binary search within the range.
+//
+// Each query symbol is added to a match once and dropped once, so the total
+// work is roughly linear in the length of the query, times the cost of the
+// searches.  The inverse suffix array and the range minimum structure are
+// built on the first call and kept by the Index.  Only the current match is
+// buffered, so the query may be arbitrarily long.
+//
+func MatchingStatistics(idx *Index, query io.Reader, fn func(MatchingStatistic) error) error {
+	rank, err := idx.rankArray()
+	if err != nil {
+		return err
+	}
+	rm, err := idx.lcpRangeMinimum()
+	if err != nil {
+		return err
+	}
+
+	text := idx.searchText()
+	br := bufio.NewReader(query)
+
+	// window holds query[offset:offset+len(window)]; the current match
+	// is its first length symbols.
+	var window []uint64
+	eof := false
+	fill := func(need int) error {
+		for !eof && len(window) < need {
+			ch, err := br.ReadByte()
+			if err == io.EOF {
+				eof = true
+				break
+			}
+			if err != nil {
+				return err
+			}
+			symbol := uint64(ch)
+			if idx.FoldCase() {
+				symbol = foldSymbol(symbol)
+			}
+			window = append(window, symbol)
+		}
+		return nil
+	}
+
+	full := batchRange{0, idx.sa.Len()}
+	current := full
+	length := uint64(0)
+
+	for offset := uint64(0); ; offset++ {
+		if err := fill(1); err != nil {
+			return err
+		}
+		if len(window) == 0 {
+			return nil
+		}
+
+		// Extend the match as far as it goes.
+		for {
+			if err := fill(int(length) + 1); err != nil {
+				return err
+			}
+			if uint64(len(window)) <= length {
+				break
+			}
+			lo, hi, err := narrowRange(text, idx.sa, current.lo, current.hi, length, window[length])
+			if err != nil {
+				return err
+			}
+			if lo >= hi {
+				break
+			}
+			current = batchRange{lo, hi}
+			length++
+		}
+
+		var pos uint64
+		if length > 0 {
+			pos, err = idx.sa.PositionAt(current.lo)
+			if err != nil {
+				return err
+			}
+		}
+		if err := fn(MatchingStatistic{offset, length, pos}); err != nil {
+			return err
+		}
+
+		// Drop the first symbol of the match.
+		window = window[1:]
+		if length <= 1 {
+			current = full
+			length = 0
+			continue
+		}
+		length--
+
+		r, err := rank.ValueAt(pos + 1)
+		if err != nil {
+			return err
+		}
+		current.lo, err = expandLeft(rm, r, length)
+		if err != nil {
+			return err
+		}
+		current.hi, err = expandRight(rm, r+1, length)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// expandLeft returns the smallest index j <= r such that LCP[k] >= h for
+// every k in (j, r], given a rangeMinimum over the LCP array.
+func expandLeft(rm *rangeMinimum, r uint64, h uint64) (uint64, error) {
+	good := func(j uint64) (bool, error) {
+		if j == r {
+			return true, nil
+		}
+		height, err := rm.Min(j+1, r+1)
+		return height >= h, err
+	}
+
+	// Double the distance until j = r-d fails, or passes the start.
+	d := uint64(1)
+	for d <= r {
+		ok, err := good(r - d)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			break
+		}
+		d *= 2
+	}
+
+	// j = r-d/2 is good, and either j = r-d is not or d > r.
+	lo := uint64(0)
+	if d <= r {
+		lo = r - d + 1
+	}
+	return searchIndices(lo, r-d/2, good)
+}
+
+// expandRight returns the largest index j >= hi such that LCP[k] >= h for
+// every k in [hi, j), given a rangeMinimum over the LCP array.
+func expandRight(rm *rangeMinimum, hi uint64, h uint64) (uint64, error) {
+	n := rm.values.Len()
+	bad := func(j uint64) (bool, error) {
+		if j == hi {
+			return false, nil
+		}
+		height, err := rm.Min(hi, j)
+		return height < h, err
+	}
+
+	// Double the distance until j = hi+d fails, or passes the end.
+	d := uint64(1)
+	for hi+d <= n {
+		notOK, err := bad(hi + d)
+		if err != nil {
+			return 0, err
+		}
+		if notOK {
+			break
+		}
+		d *= 2
+	}
+
+	// j = hi+d/2 is good, and either j = hi+d is not or hi+d > n, in
+	// which case n+1 stands in for the first bad j.
+	end := n + 1
+	if hi+d <= n {
+		end = hi + d
+	}
+	first, err := searchIndices(hi+d/2+1, end, bad)
+	return first - 1, err
+}
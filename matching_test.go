@@ -0,0 +1,146 @@
+package suffixarray
+
+import (
+	"strings"
+	"testing"
+)
+
+func NaiveMatchingStatistics(text, query string) []uint64 {
+	out := make([]uint64, len(query))
+	for i := range query {
+		for j := i + 1; j <= len(query) && strings.Contains(text, query[i:j]); j++ {
+			out[i] = uint64(j - i)
+		}
+	}
+	return out
+}
+
+func TestMatchingStatistics(t *testing.T) {
+	type testrow struct {
+		Text    string
+		Queries []string
+	}
+	repetitive := strings.Repeat("a", 700) + "b" + strings.Repeat("ab", 300)
+	rows := []testrow{
+		testrow{sampleText, []string{
+			"",
+			"zzz",
+			searchPhrase,
+			strings.Replace(sampleText[100:700], "e", "E", -1),
+			"Lorem ipsum dolor sit amet, consectetur adipiscing elit. Phasellus nec odio vitae urna tempor.",
+			sampleText[:300] + "###" + sampleText[200:500],
+		}},
+		testrow{repetitive, []string{
+			strings.Repeat("a", 800),
+			strings.Repeat("a", 200) + "b" + strings.Repeat("ab", 400) + "aab",
+			strings.Repeat("ba", 350),
+		}},
+	}
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		for _, row := range rows {
+			testMatchingStatistics(t, cfg, row.Text, row.Queries)
+		}
+	}
+}
+
+func testMatchingStatistics(t *testing.T, cfg configuration, text string, queries []string) {
+	opts := cfg.Opts
+
+	idx, err := BuildIndex(NewTextFromString(text, opts...), opts...)
+	if err != nil {
+		t.Errorf("[%s] BuildIndex: error: %v", cfg.Name, err)
+		return
+	}
+
+	for i, query := range queries {
+		expected := NaiveMatchingStatistics(text, query)
+		count := 0
+		err := MatchingStatistics(idx, strings.NewReader(query), func(ms MatchingStatistic) error {
+			if ms.Offset != uint64(count) {
+				t.Errorf("[%s/%03d] MatchingStatistics: expected offset %d, got %d", cfg.Name, i, count, ms.Offset)
+			}
+			count++
+			if ms.Offset >= uint64(len(expected)) {
+				return nil
+			}
+			if ms.Length != expected[ms.Offset] {
+				t.Errorf("[%s/%03d] MatchingStatistics: at %d: expected length %d, got %d", cfg.Name, i, ms.Offset, expected[ms.Offset], ms.Length)
+				return nil
+			}
+			match := query[ms.Offset : ms.Offset+ms.Length]
+			if ms.Position+ms.Length > uint64(len(text)) || text[ms.Position:ms.Position+ms.Length] != match {
+				t.Errorf("[%s/%03d] MatchingStatistics: at %d: position %d does not hold %q", cfg.Name, i, ms.Offset, ms.Position, match)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("[%s/%03d] MatchingStatistics: error: %v", cfg.Name, i, err)
+		}
+		if count != len(query) {
+			t.Errorf("[%s/%03d] MatchingStatistics: expected %d statistics, got %d", cfg.Name, i, len(query), count)
+		}
+	}
+
+	if err := idx.Close(); err != nil {
+		t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+	}
+}
+
+func TestExpandRange(t *testing.T) {
+	text := strings.Repeat("a", 300) + "b" + strings.Repeat("ab", 200) + sampleText[:500]
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		idx, err := BuildIndex(NewTextFromString(text, opts...), opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildIndex: error: %v", cfg.Name, err)
+			continue
+		}
+		rm, err := idx.lcpRangeMinimum()
+		if err != nil {
+			t.Errorf("[%s] lcpRangeMinimum: error: %v", cfg.Name, err)
+			idx.Close()
+			continue
+		}
+
+		var heights []uint64
+		iter := idx.lcp.Iterate(0, idx.lcp.Len())
+		for iter.Next() {
+			heights = append(heights, iter.Height())
+		}
+		if err := iter.Close(); err != nil {
+			t.Errorf("[%s] Iterate: error: %v", cfg.Name, err)
+			idx.Close()
+			continue
+		}
+
+		n := uint64(len(heights))
+		for r := uint64(0); r < n; r += 7 {
+			for _, h := range []uint64{1, 2, 3, 10, 100, 250} {
+				expectedLo := r
+				for expectedLo > 0 && heights[expectedLo] >= h {
+					expectedLo--
+				}
+				expectedHi := r + 1
+				for expectedHi < n && heights[expectedHi] >= h {
+					expectedHi++
+				}
+
+				lo, err := expandLeft(rm, r, h)
+				if err != nil || lo != expectedLo {
+					t.Errorf("[%s] expandLeft %d %d: expected %d, got %d, %v", cfg.Name, r, h, expectedLo, lo, err)
+				}
+				hi, err := expandRight(rm, r+1, h)
+				if err != nil || hi != expectedHi {
+					t.Errorf("[%s] expandRight %d %d: expected %d, got %d, %v", cfg.Name, r+1, h, expectedHi, hi, err)
+				}
+			}
+		}
+
+		if err := idx.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
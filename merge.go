@@ -0,0 +1,316 @@
+package suffixarray
+
+import (
+	"fmt"
+)
+
+// DocumentSeparator is the symbol which MergeSuffixArrays places between the
+// two merged texts.  It must not occur in either text, unless the
+// ShiftSymbols option is given.
+const DocumentSeparator = 0
+
+type mergeCursor struct {
+	base    uint64
+	saIter  *Iterator
+	lcpIter *LCPIterator
+	pos     uint64
+	height  uint64
+	valid   bool
+}
+
+func newMergeCursor(sa *SuffixArray, lcp *LCPArray, base uint64) *mergeCursor {
+	return &mergeCursor{
+		base:    base,
+		saIter:  sa.Iterate(1, sa.Len()),
+		lcpIter: lcp.Iterate(1, lcp.Len()),
+	}
+}
+
+// advance moves to the next suffix of this text.  Since the previous suffix
+// of the same text was the last one output, the LCP array already holds the
+// height of the new suffix relative to the last output.
+func (c *mergeCursor) advance() error {
+	c.valid = c.saIter.Next() && c.lcpIter.Next()
+	if !c.valid {
+		if err := c.saIter.Err(); err != nil {
+			return err
+		}
+		return c.lcpIter.Err()
+	}
+	c.pos = c.base + c.saIter.Position()
+	c.height = c.lcpIter.Height()
+	return nil
+}
+
+func (c *mergeCursor) Close() error {
+	err := c.saIter.Close()
+	if err2 := c.lcpIter.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// MergeSuffixArrays combines the suffix arrays of two texts, which may have
+// been built independently, into the suffix array of the two-document
+// collection A·S·B, where S is DocumentSeparator.  Returns the concatenated
+// Text together with its suffix array.
+//
+// By default, S must not occur in either text.  With the ShiftSymbols option,
+// every symbol of A and B is stored one higher instead, so that the texts may
+// use the whole of their alphabets.  Shifting preserves the order of the
+// symbols, so the suffix arrays of A and B remain valid.
+//
+// S must sort before every symbol of A, which is why it cannot be chosen
+// freely: a suffix of A which reaches S must sort before every longer suffix
+// of A with the same prefix, as it did when it reached the end of A.
+//
+// Because S occurs nowhere else, every suffix of A is terminated by S and no
+// comparison ever crosses from one document into the other.  The result is
+// therefore identical to calling BuildSuffixArray on A·S·B.  SA[0] is the
+// empty suffix, SA[1] is the suffix beginning with the separator at offset
+// |A|, and offsets of B are shifted by |A|+1.
+//
+// The two arrays are streamed through iterators and merged with the help of
+// their LCP arrays, which are built as temporaries.  For each side, the merge
+// tracks the length of the prefix which its next suffix shares with the last
+// suffix output.  When these lengths differ, the side with the longer shared
+// prefix sorts first without looking at the text at all; only when they are
+// equal are the two suffixes compared, and then starting past the shared
+// prefix.
+//
+func MergeSuffixArrays(textA *Text, saA *SuffixArray, textB *Text, saB *SuffixArray, opts ...Option) (*Text, *SuffixArray, error) {
+	lenA := textA.Len()
+	lenB := textB.Len()
+	if saA.Len() != lenA+1 {
+		return nil, nil, fmt.Errorf("MergeSuffixArrays: suffix array A has length %d, expected %d", saA.Len(), lenA+1)
+	}
+	if saB.Len() != lenB+1 {
+		return nil, nil, fmt.Errorf("MergeSuffixArrays: suffix array B has length %d, expected %d", saB.Len(), lenB+1)
+	}
+
+	n := lenA + 1 + lenB
+	shift := uint64(0)
+	if makeBuildOptions(opts).shiftSymbols {
+		shift = 1
+	}
+	text, err := concatenateDocuments(textA, textB, shift, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	needCloseText := true
+	defer func() {
+		if needCloseText {
+			text.Close()
+		}
+	}()
+
+	tempOpts := extendOptions(
+		opts,
+		WithFile(nil))
+
+	lcpA, err := BuildLCPArray(textA, saA, tempOpts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer lcpA.Close()
+
+	lcpB, err := BuildLCPArray(textB, saB, tempOpts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer lcpB.Close()
+
+	saOpts := extendOptions(
+		opts,
+		NumValues(n+1),
+		MaxValue(n))
+
+	sa, err := New(saOpts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	needCloseSA := true
+	defer func() {
+		if needCloseSA {
+			sa.Close()
+		}
+	}()
+
+	a := newMergeCursor(saA, lcpA, 0)
+	b := newMergeCursor(saB, lcpB, lenA+1)
+	out := sa.Iterate(0, sa.Len())
+
+	err = mergeSuffixes(text, lenA, a, b, out)
+	if err2 := a.Close(); err == nil {
+		err = err2
+	}
+	if err2 := b.Close(); err == nil {
+		err = err2
+	}
+	if err2 := out.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	needCloseText = false
+	needCloseSA = false
+	return text, sa, nil
+}
+
+// concatenateDocuments returns A·S·B, where S is DocumentSeparator, with the
+// given shift added to every symbol of A and B.
+func concatenateDocuments(textA *Text, textB *Text, shift uint64, opts []Option) (*Text, error) {
+	alphaSize := textA.AlphabetSize()
+	if textB.AlphabetSize() > alphaSize {
+		alphaSize = textB.AlphabetSize()
+	}
+	alphaSize += shift
+	if alphaSize <= DocumentSeparator {
+		alphaSize = DocumentSeparator + 1
+	}
+
+	textOpts := extendOptions(
+		opts,
+		NumValues(textA.Len()+1+textB.Len()))
+
+	text, err := NewText(alphaSize, textOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := text.Iterate(0, text.Len())
+	err = copyDocument(dst, textA, shift, "A")
+	if err == nil && dst.Next() {
+		dst.SetSymbol(DocumentSeparator)
+		err = copyDocument(dst, textB, shift, "B")
+	}
+	if err2 := dst.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		text.Close()
+		return nil, err
+	}
+	return text, nil
+}
+
+func copyDocument(dst *TextIterator, src *Text, shift uint64, name string) error {
+	iter := src.Iterate(0, src.Len())
+	for iter.Next() && dst.Next() {
+		symbol := iter.Symbol() + shift
+		if symbol == DocumentSeparator {
+			index := iter.Index()
+			iter.Close()
+			return fmt.Errorf("MergeSuffixArrays: text %s contains the document separator at offset %d", name, index)
+		}
+		dst.SetSymbol(symbol)
+	}
+	return iter.Close()
+}
+
+func mergeSuffixes(text *Text, sep uint64, a, b *mergeCursor, out *Iterator) error {
+	n := text.Len()
+
+	emit := func(pos uint64) error {
+		if !out.Next() {
+			return out.Err()
+		}
+		out.SetPosition(pos)
+		return nil
+	}
+
+	// The empty suffix sorts first, followed by the suffix beginning with
+	// the separator.  No other suffix shares a prefix with the latter,
+	// which matches LCP[1] = 0 on both sides.
+	if err := emit(n); err != nil {
+		return err
+	}
+	if err := emit(sep); err != nil {
+		return err
+	}
+
+	if err := a.advance(); err != nil {
+		return err
+	}
+	if err := b.advance(); err != nil {
+		return err
+	}
+
+	for a.valid && b.valid {
+		var takeA bool
+		switch {
+		case a.height > b.height:
+			takeA = true
+		case a.height < b.height:
+			takeA = false
+		default:
+			var height uint64
+			var err error
+			takeA, height, err = compareMergeSuffixes(text, a.pos, b.pos, a.height)
+			if err != nil {
+				return err
+			}
+			if takeA {
+				b.height = height
+			} else {
+				a.height = height
+			}
+		}
+
+		next := b
+		if takeA {
+			next = a
+		}
+		if err := emit(next.pos); err != nil {
+			return err
+		}
+		if err := next.advance(); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range []*mergeCursor{a, b} {
+		for c.valid {
+			if err := emit(c.pos); err != nil {
+				return err
+			}
+			if err := c.advance(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compareMergeSuffixes compares the suffix at i, which lies in A, with the
+// suffix at j, which lies in B, given that they share at least the first h
+// symbols.  Returns true iff suffix i sorts first, along with the length of
+// their common prefix.
+//
+// Suffix i can never run off the end of the text, because it reaches the
+// separator first, and the separator matches nothing in B.
+//
+func compareMergeSuffixes(text *Text, i, j, h uint64) (bool, uint64, error) {
+	n := text.Len()
+	for {
+		if j+h == n {
+			return false, h, nil
+		}
+		x, err := text.SymbolAt(i + h)
+		if err != nil {
+			return false, 0, err
+		}
+		y, err := text.SymbolAt(j + h)
+		if err != nil {
+			return false, 0, err
+		}
+		if x != y {
+			return x < y, h, nil
+		}
+		h++
+	}
+}
@@ -0,0 +1,168 @@
+package suffixarray
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMergeSuffixArrays(t *testing.T) {
+	type testrow struct {
+		A string
+		B string
+	}
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		for i, row := range []testrow{
+			testrow{banana, cabbage},
+			testrow{banana, banana},
+			testrow{aaaaaaaa, "aaa"},
+			testrow{"aaa", aaaaaaaa},
+			testrow{"", banana},
+			testrow{banana, ""},
+			testrow{"", ""},
+			testrow{sampleText[:1000], sampleText[1000:]},
+			testrow{sampleText[:37], sampleText[37:]},
+			testrow{sampleText, sampleText},
+		} {
+			textA := NewTextFromString(row.A, opts...)
+			textB := NewTextFromString(row.B, opts...)
+			saA, err := BuildSuffixArray(textA, opts...)
+			if err != nil {
+				t.Errorf("[%s/%03d] BuildSuffixArray: error: %v", cfg.Name, i, err)
+				continue
+			}
+			saB, err := BuildSuffixArray(textB, opts...)
+			if err != nil {
+				t.Errorf("[%s/%03d] BuildSuffixArray: error: %v", cfg.Name, i, err)
+				continue
+			}
+
+			text, sa, err := MergeSuffixArrays(textA, saA, textB, saB, opts...)
+			if err != nil {
+				t.Errorf("[%s/%03d] MergeSuffixArrays: error: %v", cfg.Name, i, err)
+				continue
+			}
+
+			combined := row.A + "\x00" + row.B
+			if expected, actual := NewTextFromString(combined, opts...).Debug(), text.Debug(); expected != actual {
+				t.Errorf("[%s/%03d] MergeSuffixArrays: expected text %s, got %s", cfg.Name, i, expected, actual)
+			}
+
+			expectedSA, err := BuildSuffixArray(text, opts...)
+			if err != nil {
+				t.Errorf("[%s/%03d] BuildSuffixArray: error: %v", cfg.Name, i, err)
+				continue
+			}
+			if expected, actual := expectedSA.Debug(), sa.Debug(); expected != actual {
+				t.Errorf("[%s/%03d] MergeSuffixArrays: expected %s, got %s", cfg.Name, i, expected, actual)
+			}
+
+			offsets, err := Search(text, sa, nil, "an")
+			if err != nil {
+				t.Errorf("[%s/%03d] Search: error: %v", cfg.Name, i, err)
+				continue
+			}
+			if expected, actual := fmt.Sprintf("%v", NaiveSearch(combined, "an")), fmt.Sprintf("%v", offsets); expected != actual {
+				t.Errorf("[%s/%03d] Search: expected %s, got %s", cfg.Name, i, expected, actual)
+			}
+		}
+
+		textA := NewTextFromString("ab\x00c", opts...)
+		textB := NewTextFromString(banana, opts...)
+		saA, _ := BuildSuffixArray(textA, opts...)
+		saB, _ := BuildSuffixArray(textB, opts...)
+		expected := "MergeSuffixArrays: text A contains the document separator at offset 2"
+		if _, _, err := MergeSuffixArrays(textA, saA, textB, saB, opts...); err == nil || err.Error() != expected {
+			t.Errorf("[%s] MergeSuffixArrays: expected error %q, got %v", cfg.Name, expected, err)
+		}
+	}
+}
+
+func TestMergeSuffixArrays_ShiftSymbols(t *testing.T) {
+	type testrow struct {
+		A string
+		B string
+	}
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		for i, row := range []testrow{
+			testrow{"ab\x00c", banana},
+			testrow{"\x00\x00a\x00", "a\x00\x00"},
+			testrow{"\xff\x00\xff", "\x00\xff\x00\xff"},
+			testrow{"", ""},
+			testrow{sampleText[:1000], sampleText[1000:]},
+		} {
+			textA := NewTextFromString(row.A, opts...)
+			textB := NewTextFromString(row.B, opts...)
+			saA, err := BuildSuffixArray(textA, opts...)
+			if err != nil {
+				t.Errorf("[%s/%03d] BuildSuffixArray: error: %v", cfg.Name, i, err)
+				continue
+			}
+			saB, err := BuildSuffixArray(textB, opts...)
+			if err != nil {
+				t.Errorf("[%s/%03d] BuildSuffixArray: error: %v", cfg.Name, i, err)
+				continue
+			}
+
+			text, sa, err := MergeSuffixArrays(textA, saA, textB, saB, extendOptions(opts, ShiftSymbols())...)
+			if err != nil {
+				t.Errorf("[%s/%03d] MergeSuffixArrays: error: %v", cfg.Name, i, err)
+				continue
+			}
+
+			if text.AlphabetSize() != 257 {
+				t.Errorf("[%s/%03d] AlphabetSize: expected 257, got %d", cfg.Name, i, text.AlphabetSize())
+			}
+			var expectedSymbols, symbols []uint64
+			for _, ch := range []byte(row.A) {
+				expectedSymbols = append(expectedSymbols, uint64(ch)+1)
+			}
+			expectedSymbols = append(expectedSymbols, DocumentSeparator)
+			for _, ch := range []byte(row.B) {
+				expectedSymbols = append(expectedSymbols, uint64(ch)+1)
+			}
+			text.ForEach(func(index, symbol uint64) error {
+				symbols = append(symbols, symbol)
+				return nil
+			})
+			if expected, actual := fmt.Sprintf("%v", expectedSymbols), fmt.Sprintf("%v", symbols); expected != actual {
+				t.Errorf("[%s/%03d] MergeSuffixArrays: expected text %s, got %s", cfg.Name, i, expected, actual)
+			}
+
+			expectedSA, err := BuildSuffixArray(text, opts...)
+			if err != nil {
+				t.Errorf("[%s/%03d] BuildSuffixArray: error: %v", cfg.Name, i, err)
+				continue
+			}
+			if expected, actual := expectedSA.Debug(), sa.Debug(); expected != actual {
+				t.Errorf("[%s/%03d] MergeSuffixArrays: expected %s, got %s", cfg.Name, i, expected, actual)
+			}
+
+			idx, err := BuildIndex(text, opts...)
+			if err != nil {
+				t.Errorf("[%s/%03d] BuildIndex: error: %v", cfg.Name, i, err)
+				continue
+			}
+			di, err := BuildDocumentIndex(idx, opts...)
+			if err != nil {
+				t.Errorf("[%s/%03d] BuildDocumentIndex: error: %v", cfg.Name, i, err)
+				idx.Close()
+				continue
+			}
+			if di.NumDocuments() != 2 {
+				t.Errorf("[%s/%03d] NumDocuments: expected 2, got %d", cfg.Name, i, di.NumDocuments())
+			}
+			if start, err := di.DocumentStart(1); err != nil || start != uint64(len(row.A)+1) {
+				t.Errorf("[%s/%03d] DocumentStart 1: expected %d, got %d, %v", cfg.Name, i, len(row.A)+1, start, err)
+			}
+			if err := di.Close(); err != nil {
+				t.Errorf("[%s/%03d] Close: error: %v", cfg.Name, i, err)
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package suffixarray
 
 import (
+	"errors"
 	"io"
 	"sync"
 
@@ -8,63 +9,175 @@ import (
 	bigbitvector "github.com/team-spectre/go-bigbitvector"
 )
 
+// Option configures the construction of a Text, array, or index.  Options are
+// usually made by the functions of this package, such as NumValues.  An
+// Option which passes a go-bigarray or go-bigbitvector option straight
+// through may also be written directly, but only with keyed fields, as in
+// Option{BigArrayOption: o}, since Option also has unexported fields.
+// Positional literals, which compiled when those two were its only fields,
+// no longer do.
 type Option struct {
 	BigArrayOption     bigarray.Option
 	BigBitVectorOption bigbitvector.Option
+	buildOption        buildOption
+	storageOption      storageOption
+}
+
+// buildOption configures the algorithms which build an index or array from a
+// Text, such as BuildIndex, BuildCompressedSuffixArray, and MergeSuffixArrays.
+// Each of them reads only the fields which concern it.
+type buildOption func(*buildOptions)
+
+type buildOptions struct {
+	// foldCase is read by BuildIndex and by the searches of an
+	// IncrementalIndex.
+	foldCase bool
+
+	// sampleRate is read by BuildCompressedSuffixArray.
+	sampleRate uint64
+
+	// shiftSymbols is read by MergeSuffixArrays.
+	shiftSymbols bool
+}
+
+// storageOption configures the Storage constructed for each array.
+type storageOption func(*storageOptions)
+
+type storageOptions struct {
+	numValues     uint64
+	maxValue      uint64
+	bytesPerValue uint8
+	factory       StorageFactory
+	packed        bool
+
+	// file is true if WithFile gave a file for the array, which makes
+	// the array unsuitable for storage which must be replaced as it grows.
+	file bool
+}
+
+// config returns the StorageConfig to pass to a StorageFactory.  As with
+// go-bigarray, a BytesPerValue implies the largest value which fits in that
+// many bytes, and a MaxValue of 0 counts as unset.
+func (o storageOptions) config() (StorageConfig, error) {
+	cfg := StorageConfig{NumValues: o.numValues, MaxValue: o.maxValue}
+	if cfg.MaxValue == 0 {
+		if o.bytesPerValue == 0 {
+			return cfg, errors.New("must specify at least one of MaxValue or BytesPerValue")
+		}
+		cfg.MaxValue = ^uint64(0) >> (64 - 8*uint(o.bytesPerValue))
+	}
+	return cfg, nil
 }
 
 func NumValues(size uint64) Option {
 	return Option{
-		bigarray.NumValues(size),
-		bigbitvector.NumValues(size),
+		BigArrayOption:     bigarray.NumValues(size),
+		BigBitVectorOption: bigbitvector.NumValues(size),
+		storageOption:      func(o *storageOptions) { o.numValues = size },
 	}
 }
 
 func MaxValue(max uint64) Option {
 	return Option{
-		bigarray.MaxValue(max),
-		nil,
+		BigArrayOption: bigarray.MaxValue(max),
+		storageOption:  func(o *storageOptions) { o.maxValue = max },
 	}
 }
 
 func BytesPerValue(bpv uint8) Option {
 	return Option{
-		bigarray.BytesPerValue(bpv),
-		nil,
+		BigArrayOption: bigarray.BytesPerValue(bpv),
+		storageOption:  func(o *storageOptions) { o.bytesPerValue = bpv },
 	}
 }
 
 func OnDiskThreshold(size uint64) Option {
 	return Option{
-		bigarray.OnDiskThreshold(size),
-		bigbitvector.OnDiskThreshold(size),
+		BigArrayOption:     bigarray.OnDiskThreshold(size),
+		BigBitVectorOption: bigbitvector.OnDiskThreshold(size),
 	}
 }
 
 func PageSize(size uint) Option {
 	return Option{
-		bigarray.PageSize(size),
-		bigbitvector.PageSize(size),
+		BigArrayOption:     bigarray.PageSize(size),
+		BigBitVectorOption: bigbitvector.PageSize(size),
 	}
 }
 
 func WithPool(pool *sync.Pool) Option {
 	return Option{
-		bigarray.WithPool(pool),
-		bigbitvector.WithPool(pool),
+		BigArrayOption:     bigarray.WithPool(pool),
+		BigBitVectorOption: bigbitvector.WithPool(pool),
 	}
 }
 
 func WithFile(file bigarray.File) Option {
 	return Option{
-		bigarray.WithFile(file),
-		bigbitvector.WithFile(file),
+		BigArrayOption:     bigarray.WithFile(file),
+		BigBitVectorOption: bigbitvector.WithFile(file),
+		storageOption:      func(o *storageOptions) { o.file = file != nil },
 	}
 }
 
 func WithReadOnlyFile(file io.ReaderAt) Option {
 	return Option{
-		bigarray.WithReadOnlyFile(file),
-		bigbitvector.WithReadOnlyFile(file),
+		BigArrayOption:     bigarray.WithReadOnlyFile(file),
+		BigBitVectorOption: bigbitvector.WithReadOnlyFile(file),
+	}
+}
+
+// WithStorage makes every array use Storage constructed by the given factory,
+// rather than go-bigarray and go-bigbitvector.  Options which only configure
+// those libraries, such as OnDiskThreshold, WithFile, and WithReadOnlyFile, are then
+// ignored.
+//
+// The factory is also used for the temporary arrays of a construction, so it
+// should be given to BuildIndex and friends along with the Text's own options.
+//
+func WithStorage(factory StorageFactory) Option {
+	return Option{
+		storageOption: func(o *storageOptions) { o.factory = factory },
+	}
+}
+
+// PackedSymbols makes NewText store each symbol in only as many bits as the
+// alphabet needs, ceil(log2(AlphabetSize)), rather than in whole bytes.  A
+// DNA text over 4 symbols then takes 2 bits per base instead of 8.  Packed
+// texts are read through the same API, at some cost in speed.
+func PackedSymbols() Option {
+	return Option{
+		storageOption: func(o *storageOptions) { o.packed = true },
+	}
+}
+
+// FoldCase makes BuildIndex index the text with ASCII letters folded to lower
+// case, so that searches for phrases and patterns on the Index are
+// case-insensitive.  The original text is retained for display.  See
+// BuildIndex for the searches which behave differently.  FoldCase requires a
+// text of bytes.
+func FoldCase() Option {
+	return Option{
+		buildOption: func(o *buildOptions) { o.foldCase = true },
+	}
+}
+
+// SampleRate sets how often BuildCompressedSuffixArray stores an offset in
+// full: one offset in every rate.  Larger rates make the array smaller and
+// lookups slower.  The default is DefaultSampleRate.
+func SampleRate(rate uint64) Option {
+	return Option{
+		buildOption: func(o *buildOptions) { o.sampleRate = rate },
+	}
+}
+
+// ShiftSymbols makes MergeSuffixArrays store each symbol s of the merged texts
+// as s+1, and so grow the alphabet by one, leaving DocumentSeparator free.
+// This allows merging texts in which every symbol may occur, such as those
+// built by Compact.  Phrases searched for in the merged Text must be shifted
+// likewise.
+func ShiftSymbols() Option {
+	return Option{
+		buildOption: func(o *buildOptions) { o.shiftSymbols = true },
 	}
 }
@@ -0,0 +1,236 @@
+package suffixarray
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+)
+
+// packedStorage is a Storage which packs its values into 64-bit words using
+// only as many bits per value as MaxValue needs, e.g. 2 bits for the symbols
+// of a DNA text rather than a whole byte.  A value never straddles two words,
+// so widths which do not divide 64 leave a few bits of each word unused.
+//
+// The words themselves are kept in another Storage, so packed arrays can
+// still live on disk or come from a StorageFactory.  Setting a value rewrites
+// its whole word, so concurrent writes to neighboring values are not safe.
+//
+type packedStorage struct {
+	words   Storage
+	n       uint64
+	max     uint64
+	width   uint
+	perWord uint64
+	mask    uint64
+}
+
+// makePackedStorage constructs a packedStorage from a list of options.
+func makePackedStorage(list []Option) (Storage, error) {
+	cfg, err := makeStorageOptions(list).config()
+	if err != nil {
+		return nil, err
+	}
+
+	width := uint(bits.Len64(cfg.MaxValue))
+	if width == 0 {
+		width = 1
+	}
+	perWord := uint64(64 / width)
+
+	words, err := makeStorage(extendOptions(
+		list,
+		NumValues((cfg.NumValues+perWord-1)/perWord),
+		MaxValue(math.MaxUint64)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &packedStorage{
+		words:   words,
+		n:       cfg.NumValues,
+		max:     cfg.MaxValue,
+		width:   width,
+		perWord: perWord,
+		mask:    ^uint64(0) >> (64 - width),
+	}, nil
+}
+
+func (s *packedStorage) MaxValue() uint64 { return s.max }
+
+func (s *packedStorage) Len() uint64 { return s.n }
+
+func (s *packedStorage) ValueAt(index uint64) (uint64, error) {
+	if index >= s.n {
+		return placeholder, io.EOF
+	}
+	word, err := s.words.ValueAt(index / s.perWord)
+	if err != nil {
+		return placeholder, err
+	}
+	return s.unpack(word, index), nil
+}
+
+func (s *packedStorage) SetValueAt(index uint64, value uint64) error {
+	if value > s.max {
+		return fmt.Errorf("packedStorage.SetValueAt: value %d exceeds maximum %d", value, s.max)
+	}
+	if index >= s.n {
+		return io.EOF
+	}
+	word, err := s.words.ValueAt(index / s.perWord)
+	if err != nil {
+		return err
+	}
+	return s.words.SetValueAt(index/s.perWord, s.pack(word, index, value))
+}
+
+func (s *packedStorage) shift(index uint64) uint {
+	return uint(index%s.perWord) * s.width
+}
+
+func (s *packedStorage) unpack(word, index uint64) uint64 {
+	return (word >> s.shift(index)) & s.mask
+}
+
+func (s *packedStorage) pack(word, index, value uint64) uint64 {
+	shift := s.shift(index)
+	return word&^(s.mask<<shift) | value<<shift
+}
+
+func (s *packedStorage) Iterate(i, j uint64) StorageIterator {
+	if i > j || j > s.n {
+		panic(fmt.Errorf("packedStorage.Iterate: invalid range [%d, %d) for length %d", i, j, s.n))
+	}
+	wi, wj := i/s.perWord, (j+s.perWord-1)/s.perWord
+	return &packedIterator{
+		s:        s,
+		wordIter: s.words.Iterate(wi, wj),
+		wordPos:  wi - 1,
+		next:     i,
+		end:      j,
+		step:     1,
+	}
+}
+
+func (s *packedStorage) ReverseIterate(i, j uint64) StorageIterator {
+	if i > j || j > s.n {
+		panic(fmt.Errorf("packedStorage.ReverseIterate: invalid range [%d, %d) for length %d", i, j, s.n))
+	}
+	wi, wj := i/s.perWord, (j+s.perWord-1)/s.perWord
+	return &packedIterator{
+		s:        s,
+		wordIter: s.words.ReverseIterate(wi, wj),
+		wordPos:  wj,
+		next:     j - 1,
+		end:      i - 1,
+		step:     ^uint64(0),
+	}
+}
+
+func (s *packedStorage) Truncate(length uint64) error {
+	if length > s.n {
+		return fmt.Errorf("packedStorage.Truncate: length %d exceeds current length %d", length, s.n)
+	}
+	if err := s.words.Truncate((length + s.perWord - 1) / s.perWord); err != nil {
+		return err
+	}
+	s.n = length
+	return nil
+}
+
+func (s *packedStorage) Freeze() error { return s.words.Freeze() }
+
+func (s *packedStorage) Flush() error { return s.words.Flush() }
+
+func (s *packedStorage) Close() error { return s.words.Close() }
+
+// packedIterator walks a packedStorage, moving an iterator over the words in
+// step.  As with sliceIterator, indices wrap around in the reverse direction,
+// and so does wordPos before the first word has been reached.
+type packedIterator struct {
+	s        *packedStorage
+	wordIter StorageIterator
+	wordPos  uint64
+	word     uint64
+	index    uint64
+	next     uint64
+	end      uint64
+	step     uint64
+	err      error
+}
+
+func (iter *packedIterator) Next() bool { return iter.Skip(1) }
+
+func (iter *packedIterator) Skip(n uint64) bool {
+	if iter.err != nil {
+		return false
+	}
+	remaining := iter.end - iter.next
+	if iter.step != 1 {
+		remaining = iter.next - iter.end
+	}
+	if n > remaining {
+		iter.next = iter.end
+		return false
+	}
+	if n == 0 {
+		return true
+	}
+
+	iter.index = iter.next + (n-1)*iter.step
+	iter.next = iter.index + iter.step
+
+	target := iter.index / iter.s.perWord
+	if target != iter.wordPos {
+		delta := target - iter.wordPos
+		if iter.step != 1 {
+			delta = iter.wordPos - target
+		}
+		if !iter.wordIter.Skip(delta) {
+			iter.err = iter.wordIter.Err()
+			if iter.err == nil {
+				iter.err = io.ErrUnexpectedEOF
+			}
+			return false
+		}
+		iter.wordPos = target
+		iter.word = iter.wordIter.Value()
+	}
+	return true
+}
+
+func (iter *packedIterator) Index() uint64 { return iter.index }
+
+func (iter *packedIterator) Value() uint64 { return iter.s.unpack(iter.word, iter.index) }
+
+func (iter *packedIterator) SetValue(value uint64) {
+	if iter.err != nil {
+		return
+	}
+	if value > iter.s.max {
+		iter.err = fmt.Errorf("packedStorage.SetValue: value %d exceeds maximum %d", value, iter.s.max)
+		return
+	}
+	iter.word = iter.s.pack(iter.word, iter.index, value)
+	iter.wordIter.SetValue(iter.word)
+}
+
+func (iter *packedIterator) Err() error { return iter.err }
+
+func (iter *packedIterator) Flush() error {
+	if err := iter.wordIter.Flush(); err != nil {
+		return err
+	}
+	return iter.err
+}
+
+func (iter *packedIterator) Close() error {
+	err := iter.wordIter.Close()
+	if iter.err != nil {
+		return iter.err
+	}
+	return err
+}
+
+var _ Storage = (*packedStorage)(nil)
@@ -0,0 +1,137 @@
+package suffixarray
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPackedStorage(t *testing.T) {
+	for _, maxValue := range []uint64{1, 3, 6, 100, 1 << 40} {
+		const n = 300
+		values := make([]uint64, n)
+		for i := range values {
+			values[i] = uint64(i*7919) % (maxValue + 1)
+		}
+
+		for _, cfg := range configurations {
+			name := fmt.Sprintf("%s/%d", cfg.Name, maxValue)
+			s, err := makePackedStorage(extendOptions(cfg.Opts, NumValues(n), MaxValue(maxValue)))
+			if err != nil {
+				t.Errorf("[%s] makePackedStorage: error: %v", name, err)
+				continue
+			}
+
+			iter := s.Iterate(0, n)
+			for iter.Next() {
+				iter.SetValue(values[iter.Index()])
+			}
+			if err := iter.Close(); err != nil {
+				t.Errorf("[%s] Iterate: error: %v", name, err)
+			}
+			for i, expected := range values {
+				if actual, err := s.ValueAt(uint64(i)); err != nil || actual != expected {
+					t.Errorf("[%s] ValueAt %d: expected %d, got %d, %v", name, i, expected, actual, err)
+					break
+				}
+			}
+
+			if err := s.SetValueAt(17, maxValue); err != nil {
+				t.Errorf("[%s] SetValueAt: error: %v", name, err)
+			}
+			values[17] = maxValue
+			if err := s.SetValueAt(18, maxValue+1); err == nil {
+				t.Errorf("[%s] SetValueAt: expected error for value above MaxValue", name)
+			}
+
+			var expected, actual []uint64
+			for i := 250; i >= 3; i -= 7 {
+				expected = append(expected, values[i])
+			}
+			iter = s.ReverseIterate(3, 251)
+			for iter.Skip(1) {
+				actual = append(actual, iter.Value())
+				iter.Skip(6)
+			}
+			if err := iter.Close(); err != nil {
+				t.Errorf("[%s] ReverseIterate: error: %v", name, err)
+			}
+			if e, a := fmt.Sprintf("%v", expected), fmt.Sprintf("%v", actual); e != a {
+				t.Errorf("[%s] ReverseIterate: expected %s, got %s", name, e, a)
+			}
+
+			if err := s.Truncate(100); err != nil || s.Len() != 100 {
+				t.Errorf("[%s] Truncate: expected length 100, got %d, %v", name, s.Len(), err)
+			}
+			if _, err := s.ValueAt(100); err == nil {
+				t.Errorf("[%s] ValueAt: expected error past the end", name)
+			}
+			if err := s.Close(); err != nil {
+				t.Errorf("[%s] Close: error: %v", name, err)
+			}
+		}
+	}
+}
+
+func TestPackedSymbols_EndToEnd(t *testing.T) {
+	const dna = "ACGT"
+	var str []byte
+	for i := 0; i < 2000; i++ {
+		str = append(str, dna[(i*i+i/7)%4])
+	}
+	str = append(str, "GATTACA"...)
+
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := extendOptions(cfg.Opts, PackedSymbols())
+
+		text, err := NewText(4, extendOptions(opts, NumValues(uint64(len(str))))...)
+		if err != nil {
+			t.Errorf("[%s] NewText: error: %v", cfg.Name, err)
+			continue
+		}
+		ps, ok := text.ba.(*packedStorage)
+		if !ok || ps.width != 2 || ps.words.Len() != uint64(len(str)+31)/32 {
+			t.Errorf("[%s] NewText: expected 2 bits per symbol, got %#v", cfg.Name, text.ba)
+		}
+		iter := text.Iterate(0, text.Len())
+		for iter.Next() {
+			iter.SetSymbol(uint64(strings.IndexByte(dna, str[iter.Index()])))
+		}
+		if err := iter.Close(); err != nil {
+			t.Errorf("[%s] SetSymbol: error: %v", cfg.Name, err)
+		}
+
+		sa, err := BuildSuffixArray(text, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildSuffixArray: error: %v", cfg.Name, err)
+			continue
+		}
+		if err := VerifySuffixArray(text, sa, opts...); err != nil {
+			t.Errorf("[%s] VerifySuffixArray: error: %v", cfg.Name, err)
+		}
+
+		for _, phrase := range []string{"GATTACA", "ACGT", "TTTT", "C"} {
+			symbols := make([]uint64, len(phrase))
+			for i := range phrase {
+				symbols[i] = uint64(strings.IndexByte(dna, phrase[i]))
+			}
+			expected := fmt.Sprintf("%v", NaiveSearch(string(str), phrase))
+			offsets, err := SearchSymbols(text, sa, nil, symbols)
+			if err != nil {
+				t.Errorf("[%s] SearchSymbols %q: error: %v", cfg.Name, phrase, err)
+				continue
+			}
+			if actual := fmt.Sprintf("%v", offsets); expected != actual {
+				t.Errorf("[%s] SearchSymbols %q: expected %s, got %s", cfg.Name, phrase, expected, actual)
+			}
+		}
+
+		if err := sa.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+		if err := text.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package suffixarray
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SymbolRange is an inclusive range of symbols.
+type SymbolRange struct {
+	First uint64
+	Last  uint64
+}
+
+// PatternElement matches any one symbol which lies in one of its ranges.
+type PatternElement []SymbolRange
+
+// AnySymbol is a PatternElement which matches every symbol.
+var AnySymbol = PatternElement{{0, math.MaxUint64}}
+
+// Contains returns true iff the element matches the given symbol.
+func (elem PatternElement) Contains(symbol uint64) bool {
+	for _, r := range elem {
+		if symbol >= r.First && symbol <= r.Last {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize returns the element's ranges sorted, with overlapping and
+// adjacent ranges merged.
+func (elem PatternElement) normalize() PatternElement {
+	sorted := make(PatternElement, len(elem))
+	copy(sorted, elem)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].First < sorted[j].First })
+
+	out := sorted[:0]
+	for _, r := range sorted {
+		if n := len(out); n > 0 && (out[n-1].Last == math.MaxUint64 || r.First <= out[n-1].Last+1) {
+			if r.Last > out[n-1].Last {
+				out[n-1].Last = r.Last
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// complement returns an element matching exactly the symbols which this
+// (normalized) element does not.
+func (elem PatternElement) complement() PatternElement {
+	var out PatternElement
+	next := uint64(0)
+	for _, r := range elem {
+		if r.First > next {
+			out = append(out, SymbolRange{next, r.First - 1})
+		}
+		if r.Last == math.MaxUint64 {
+			return out
+		}
+		next = r.Last + 1
+	}
+	return append(out, SymbolRange{next, math.MaxUint64})
+}
+
+// Pattern is a sequence of elements, each of which matches one symbol of the
+// text.  It generalizes a literal phrase with wildcards and symbol classes.
+type Pattern []PatternElement
+
+// LiteralPattern returns a Pattern which matches exactly the given symbols.
+func LiteralPattern(symbols []uint64) Pattern {
+	pattern := make(Pattern, len(symbols))
+	for i, symbol := range symbols {
+		pattern[i] = PatternElement{{symbol, symbol}}
+	}
+	return pattern
+}
+
+// ParsePattern parses a pattern over a text of bytes.  The syntax is:
+//
+//   ?        any symbol
+//   [abc]    any one of the listed symbols
+//   [a-z]    any symbol in the given range
+//   [^abc]   any symbol not listed
+//   \c       the symbol c itself, e.g. \? or \[
+//   c        any other byte stands for itself
+//
+// Ranges and single symbols may be mixed freely within a class, e.g.
+// "id=[0-9a-f_]".
+//
+func ParsePattern(str string) (Pattern, error) {
+	var pattern Pattern
+	for i := 0; i < len(str); i++ {
+		switch ch := str[i]; ch {
+		case '?':
+			pattern = append(pattern, AnySymbol)
+
+		case '[':
+			elem, next, err := parsePatternClass(str, i+1)
+			if err != nil {
+				return nil, err
+			}
+			pattern = append(pattern, elem)
+			i = next
+
+		case '\\':
+			if i+1 >= len(str) {
+				return nil, fmt.Errorf("ParsePattern: %q: trailing backslash", str)
+			}
+			i++
+			pattern = append(pattern, PatternElement{{uint64(str[i]), uint64(str[i])}})
+
+		default:
+			pattern = append(pattern, PatternElement{{uint64(ch), uint64(ch)}})
+		}
+	}
+	return pattern, nil
+}
+
+// parsePatternClass parses the body of a class starting at str[i], just past
+// the '['.  Returns the element and the index of the closing ']'.
+func parsePatternClass(str string, i int) (PatternElement, int, error) {
+	negate := false
+	if i < len(str) && str[i] == '^' {
+		negate = true
+		i++
+	}
+
+	var elem PatternElement
+	for ; i < len(str) && str[i] != ']'; i++ {
+		first := str[i]
+		if first == '\\' {
+			if i+1 >= len(str) {
+				return nil, 0, fmt.Errorf("ParsePattern: %q: trailing backslash", str)
+			}
+			i++
+			first = str[i]
+		}
+
+		last := first
+		if i+2 < len(str) && str[i+1] == '-' && str[i+2] != ']' {
+			i += 2
+			last = str[i]
+			if last == '\\' {
+				if i+1 >= len(str) {
+					return nil, 0, fmt.Errorf("ParsePattern: %q: trailing backslash", str)
+				}
+				i++
+				last = str[i]
+			}
+			if last < first {
+				return nil, 0, fmt.Errorf("ParsePattern: %q: invalid range %q-%q", str, first, last)
+			}
+		}
+		elem = append(elem, SymbolRange{uint64(first), uint64(last)})
+	}
+	if i >= len(str) {
+		return nil, 0, fmt.Errorf("ParsePattern: %q: missing ']'", str)
+	}
+	if len(elem) == 0 {
+		return nil, 0, fmt.Errorf("ParsePattern: %q: empty class", str)
+	}
+
+	elem = elem.normalize()
+	if negate {
+		elem = elem.complement()
+	}
+	return elem, i, nil
+}
+
+type patternSearch struct {
+	text    *Text
+	sa      *SuffixArray
+	pattern Pattern
+	results []uint64
+}
+
+// SearchPattern returns the list of offsets into the indexed text which begin
+// with a match for the given pattern.
+//
+// The search narrows a range of the suffix array one pattern element at a
+// time, just as Range does for a phrase, except that an element matching
+// several symbols selects a band of the range rather than a single symbol.
+// The suffixes in that band no longer share a common prefix, so the search
+// then branches into one child range per distinct symbol before moving on to
+// the next element.  Runs of single-symbol elements are matched without any
+// branching.
+//
+func SearchPattern(idx *Index, pattern Pattern) ([]uint64, error) {
+	normalized := make(Pattern, len(pattern))
+	for i, elem := range pattern {
+		if idx.FoldCase() {
+			elem = foldElement(elem)
+		}
+		normalized[i] = elem.normalize()
+	}
+
+	s := &patternSearch{
+		text:    idx.searchText(),
+		sa:      idx.sa,
+		pattern: normalized,
+	}
+	if err := s.visit(0, idx.sa.Len(), 0); err != nil {
+		return nil, err
+	}
+	sort.Sort(byU64(s.results))
+	return s.results, nil
+}
+
+func (s *patternSearch) visit(lo, hi, depth uint64) error {
+	m := uint64(len(s.pattern))
+
+	var err error
+	for depth < m && lo < hi {
+		elem := s.pattern[depth]
+		if len(elem) != 1 || elem[0].First != elem[0].Last {
+			break
+		}
+		lo, hi, err = narrowRange(s.text, s.sa, lo, hi, depth, elem[0].First)
+		if err != nil {
+			return err
+		}
+		depth++
+	}
+
+	if lo >= hi {
+		return nil
+	}
+	if depth == m {
+		return s.collect(lo, hi)
+	}
+
+	for _, r := range s.pattern[depth] {
+		bandLo, bandHi, err := narrowRangeSet(s.text, s.sa, lo, hi, depth, r.First, r.Last)
+		if err != nil {
+			return err
+		}
+
+		// At the last element, there is no need to keep suffixes with
+		// different symbols apart.
+		if depth+1 == m {
+			if err := s.collect(bandLo, bandHi); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for bandLo < bandHi {
+			_, childLo, childHi, ok, err := firstChildRange(s.text, s.sa, bandLo, bandHi, depth)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				break
+			}
+			if err := s.visit(childLo, childHi, depth+1); err != nil {
+				return err
+			}
+			bandLo = childHi
+		}
+	}
+	return nil
+}
+
+func (s *patternSearch) collect(lo, hi uint64) error {
+	if lo >= hi {
+		return nil
+	}
+	iter := s.sa.Iterate(lo, hi)
+	for iter.Next() {
+		s.results = append(s.results, iter.Position())
+	}
+	return iter.Close()
+}
@@ -0,0 +1,105 @@
+package suffixarray
+
+import (
+	"fmt"
+	"testing"
+)
+
+func NaiveSearchPattern(text string, pattern Pattern) []uint64 {
+	var out []uint64
+	for i := 0; i+len(pattern) <= len(text); i++ {
+		match := true
+		for j, elem := range pattern {
+			if !elem.Contains(uint64(text[i+j])) {
+				match = false
+				break
+			}
+		}
+		if match {
+			out = append(out, uint64(i))
+		}
+	}
+	return out
+}
+
+func TestParsePattern(t *testing.T) {
+	type testrow struct {
+		Input    string
+		Expected string
+	}
+	for i, row := range []testrow{
+		testrow{"ab", "[[{97 97}] [{98 98}]]"},
+		testrow{"a?", "[[{97 97}] [{0 18446744073709551615}]]"},
+		testrow{`\?`, "[[{63 63}]]"},
+		testrow{"[0-9a]", "[[{48 57} {97 97}]]"},
+		testrow{"[ba-c]", "[[{97 99}]]"},
+		testrow{"[^b-y]", "[[{0 97} {122 18446744073709551615}]]"},
+		testrow{`[\]-]`, "[[{45 45} {93 93}]]"},
+		testrow{"[abc", `ParsePattern: "[abc": missing ']'`},
+		testrow{"[]", `ParsePattern: "[]": empty class`},
+		testrow{"[z-a]", `ParsePattern: "[z-a]": invalid range 'z'-'a'`},
+		testrow{`ab\`, `ParsePattern: "ab\\": trailing backslash`},
+		testrow{`[a\`, `ParsePattern: "[a\\": trailing backslash`},
+		testrow{`[a-\`, `ParsePattern: "[a-\\": trailing backslash`},
+		testrow{`[A-\]`, `ParsePattern: "[A-\\]": missing ']'`},
+		testrow{`[\\]`, "[[{92 92}]]"},
+	} {
+		pattern, err := ParsePattern(row.Input)
+		actual := fmt.Sprintf("%v", pattern)
+		if err != nil {
+			actual = err.Error()
+		}
+		if row.Expected != actual {
+			t.Errorf("[%03d] ParsePattern %q: expected %s, got %s", i, row.Input, row.Expected, actual)
+		}
+	}
+}
+
+func TestSearchPattern(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		idx, err := BuildIndex(NewTextFromString(sampleText, opts...), opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildIndex: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for i, str := range []string{
+			searchPhrase,
+			"od?o",
+			"d[aeiou]l",
+			"[A-Z]hasellus",
+			"s??",
+			"?",
+			"[^a-z ]",
+			"id=[0-9][0-9]",
+			"a[m-n]?t",
+			"??[.,]",
+			`\?`,
+		} {
+			pattern, err := ParsePattern(str)
+			if err != nil {
+				t.Errorf("[%s/%03d] ParsePattern %q: error: %v", cfg.Name, i, str, err)
+				continue
+			}
+
+			offsets, err := SearchPattern(idx, pattern)
+			if err != nil {
+				t.Errorf("[%s/%03d] SearchPattern %q: error: %v", cfg.Name, i, str, err)
+				continue
+			}
+
+			expected := fmt.Sprintf("%v", NaiveSearchPattern(sampleText, pattern))
+			actual := fmt.Sprintf("%v", offsets)
+			if expected != actual {
+				t.Errorf("[%s/%03d] SearchPattern %q: expected %s, got %s", cfg.Name, i, str, expected, actual)
+			}
+		}
+
+		if err := idx.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
@@ -0,0 +1,439 @@
+package suffixarray
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"unicode"
+)
+
+// maxExactLiterals bounds the number of alternative literals tracked for one
+// piece of a regexp, e.g. for `[ab][cd]` or `(?i)abc`.  Beyond this, the
+// literals collected so far are turned into a query and a new piece begins.
+const maxExactLiterals = 16
+
+// minQueryLiteral is the length, in bytes, of the shortest literal worth
+// looking up.  Shorter literals occur nearly everywhere, so the lookup would
+// cost more than it saves.
+const minQueryLiteral = 3
+
+// maxClassRunes bounds the size of a character class which is expanded into
+// its individual runes.
+const maxClassRunes = 8
+
+type queryOp byte
+
+const (
+	queryAll queryOp = iota
+	queryNone
+	queryLiteral
+	queryAnd
+	queryOr
+)
+
+// regexpQuery is a boolean combination of literals, all of which a line must
+// contain if it is to match a regexp.
+type regexpQuery struct {
+	op      queryOp
+	literal string
+	sub     []*regexpQuery
+}
+
+var (
+	matchAll  = &regexpQuery{op: queryAll}
+	matchNone = &regexpQuery{op: queryNone}
+)
+
+func andQuery(a, b *regexpQuery) *regexpQuery {
+	switch {
+	case a.op == queryNone || b.op == queryNone:
+		return matchNone
+	case a.op == queryAll:
+		return b
+	case b.op == queryAll:
+		return a
+	}
+	return &regexpQuery{op: queryAnd, sub: []*regexpQuery{a, b}}
+}
+
+func orQuery(a, b *regexpQuery) *regexpQuery {
+	switch {
+	case a.op == queryAll || b.op == queryAll:
+		return matchAll
+	case a.op == queryNone:
+		return b
+	case b.op == queryNone:
+		return a
+	}
+	return &regexpQuery{op: queryOr, sub: []*regexpQuery{a, b}}
+}
+
+// regexpInfo summarizes what is known about the strings matched by a piece of
+// a regexp.
+type regexpInfo struct {
+	// exact, if known, is the complete set of strings the piece can match.
+	exact      []string
+	exactKnown bool
+
+	// match is a query which any line containing a match must satisfy, in
+	// addition to containing one of the exact strings, if known.
+	match *regexpQuery
+}
+
+func exactInfo(strs ...string) regexpInfo {
+	return regexpInfo{exact: strs, exactKnown: true, match: matchAll}
+}
+
+func inexactInfo(match *regexpQuery) regexpInfo {
+	return regexpInfo{match: match}
+}
+
+func (info regexpInfo) query() *regexpQuery {
+	if !info.exactKnown {
+		return info.match
+	}
+	q := matchNone
+	for _, str := range info.exact {
+		if len(str) < minQueryLiteral {
+			q = matchAll
+			break
+		}
+		q = orQuery(q, &regexpQuery{op: queryLiteral, literal: str})
+	}
+	return andQuery(info.match, q)
+}
+
+func analyzeRegexp(re *syntax.Regexp) regexpInfo {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return exactInfo()
+
+	case syntax.OpEmptyMatch,
+		syntax.OpBeginLine, syntax.OpEndLine,
+		syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return exactInfo("")
+
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			info := exactInfo("")
+			for _, r := range re.Rune {
+				info = concatInfo(info, exactInfo(foldRune(r)...))
+			}
+			return info
+		}
+		return exactInfo(string(re.Rune))
+
+	case syntax.OpCharClass:
+		var strs []string
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			lo, hi := re.Rune[i], re.Rune[i+1]
+			if int(hi-lo)+1+len(strs) > maxClassRunes {
+				return inexactInfo(matchAll)
+			}
+			for r := lo; r <= hi; r++ {
+				strs = append(strs, string(r))
+			}
+		}
+		return exactInfo(strs...)
+
+	case syntax.OpCapture:
+		return analyzeRegexp(re.Sub[0])
+
+	case syntax.OpPlus:
+		return inexactInfo(analyzeRegexp(re.Sub[0]).query())
+
+	case syntax.OpRepeat:
+		if re.Min == 0 {
+			return inexactInfo(matchAll)
+		}
+		return inexactInfo(analyzeRegexp(re.Sub[0]).query())
+
+	case syntax.OpConcat:
+		info := exactInfo("")
+		for _, sub := range re.Sub {
+			info = concatInfo(info, analyzeRegexp(sub))
+		}
+		return info
+
+	case syntax.OpAlternate:
+		info := regexpInfo{exactKnown: true, match: matchNone}
+		for _, sub := range re.Sub {
+			info = alternateInfo(info, analyzeRegexp(sub))
+		}
+		return info
+	}
+
+	// OpAnyChar, OpAnyCharNotNL, OpStar, OpQuest: anything goes.
+	return inexactInfo(matchAll)
+}
+
+func concatInfo(x, y regexpInfo) regexpInfo {
+	if x.exactKnown && y.exactKnown && len(x.exact)*len(y.exact) <= maxExactLiterals {
+		strs := make([]string, 0, len(x.exact)*len(y.exact))
+		for _, a := range x.exact {
+			for _, b := range y.exact {
+				strs = append(strs, a+b)
+			}
+		}
+		info := exactInfo(strs...)
+		info.match = andQuery(x.match, y.match)
+		return info
+	}
+
+	// Too many combinations: turn what is known about x into a query, and
+	// carry on with y.
+	if y.exactKnown {
+		info := exactInfo(y.exact...)
+		info.match = andQuery(x.query(), y.match)
+		return info
+	}
+	return inexactInfo(andQuery(x.query(), y.query()))
+}
+
+func alternateInfo(x, y regexpInfo) regexpInfo {
+	if x.exactKnown && y.exactKnown && len(x.exact)+len(y.exact) <= maxExactLiterals {
+		strs := make([]string, 0, len(x.exact)+len(y.exact))
+		strs = append(strs, x.exact...)
+		strs = append(strs, y.exact...)
+		info := exactInfo(strs...)
+		info.match = orQuery(x.match, y.match)
+		return info
+	}
+	return inexactInfo(orQuery(x.query(), y.query()))
+}
+
+// foldRune returns every case variant of a rune.
+func foldRune(r rune) []string {
+	variants := []string{string(r)}
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		variants = append(variants, string(f))
+	}
+	return variants
+}
+
+type regexpSearch struct {
+	idx *Index
+	re  *regexp.Regexp
+}
+
+// SearchRegexp returns the matches of a regular expression in the indexed
+// text, which must be a text of bytes; SearchRegexp returns an error if the
+// alphabet is larger than 256 symbols.  As with grep, the regexp is applied
+// to each line separately, without its trailing newline, so matches never
+// span lines and ^ and $ match at line boundaries.  The matches are sorted by
+// offset and have a Distance of 0.
+//
+// Rather than running the regexp over the entire text, SearchRegexp first
+// derives from the parsed regexp a boolean query of literal strings which
+// every matching line must contain, such as ("sit " OR "set ") AND " amet" for
+// `s[ie]t \w+ amet`.  Each literal is located with Search, and only the lines
+// satisfying the query are handed to the regexp.  If nothing useful can be
+// derived, as for `.*`, every line is checked.
+//
+// The matches themselves are found by re, so it may have been compiled by
+// either Compile or CompilePOSIX, or made leftmost-longest by Longest.  Since
+// a Regexp does not reveal the flags it was compiled with, the query is
+// derived by parsing re.String() again with Perl flags, which accept every
+// POSIX regexp.  The query only decides which lines can contain a match, and
+// which match is preferred within a line does not change that.  Flags within
+// the regexp, such as (?s), are honored, but cannot make a match span lines.
+//
+// On an Index built with FoldCase, the literals are looked up without regard
+// to case, but the regexp is still applied to the original text as it is, so
+// it only ignores case if it says so with (?i).
+//
+func SearchRegexp(idx *Index, re *regexp.Regexp) ([]Match, error) {
+	if idx.text.AlphabetSize() > 256 {
+		return nil, fmt.Errorf("SearchRegexp: alphabet size %d exceeds 256", idx.text.AlphabetSize())
+	}
+
+	query := matchAll
+	if parsed, err := syntax.Parse(re.String(), syntax.Perl); err == nil {
+		query = analyzeRegexp(parsed.Simplify()).query()
+	}
+
+	s := &regexpSearch{idx: idx, re: re}
+	lines, all, err := s.eval(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Match
+	if all {
+		var line []byte
+		start := uint64(0)
+		err = idx.text.ForEach(func(index uint64, symbol uint64) error {
+			if symbol == '\n' {
+				results = s.verify(results, start, line)
+				line = line[:0]
+				start = index + 1
+				return nil
+			}
+			line = append(line, byte(symbol))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		results = s.verify(results, start, line)
+		return results, nil
+	}
+
+	for _, start := range lines {
+		line, err := s.readLine(start)
+		if err != nil {
+			return nil, err
+		}
+		results = s.verify(results, start, line)
+	}
+	return results, nil
+}
+
+func (s *regexpSearch) verify(results []Match, start uint64, line []byte) []Match {
+	for _, loc := range s.re.FindAllIndex(line, -1) {
+		results = append(results, Match{
+			Offset: start + uint64(loc[0]),
+			Length: uint64(loc[1] - loc[0]),
+		})
+	}
+	return results
+}
+
+// eval returns the sorted offsets of the lines which satisfy the query, or
+// true if every line does.
+func (s *regexpSearch) eval(q *regexpQuery) ([]uint64, bool, error) {
+	switch q.op {
+	case queryAll:
+		return nil, true, nil
+
+	case queryNone:
+		return nil, false, nil
+
+	case queryLiteral:
+		literal := s.idx.prepare(stringToSymbols(q.literal))
+		offsets, err := searchSymbols(s.idx.searchText(), s.idx.sa, s.idx.lcplr, literal)
+		if err != nil {
+			return nil, false, err
+		}
+		lines, err := s.lineStarts(offsets)
+		return lines, false, err
+
+	case queryAnd:
+		var lines []uint64
+		all := true
+		for _, sub := range q.sub {
+			subLines, subAll, err := s.eval(sub)
+			if err != nil {
+				return nil, false, err
+			}
+			if subAll {
+				continue
+			}
+			if all {
+				lines, all = subLines, false
+			} else {
+				lines = intersectSorted(lines, subLines)
+			}
+			if len(lines) == 0 {
+				return nil, false, nil
+			}
+		}
+		return lines, all, nil
+
+	case queryOr:
+		var lines []uint64
+		for _, sub := range q.sub {
+			subLines, subAll, err := s.eval(sub)
+			if err != nil {
+				return nil, false, err
+			}
+			if subAll {
+				return nil, true, nil
+			}
+			lines = unionSorted(lines, subLines)
+		}
+		return lines, false, nil
+	}
+	panic("BUG")
+}
+
+// lineStarts maps a sorted list of offsets to the sorted, distinct offsets
+// of the lines containing them.
+func (s *regexpSearch) lineStarts(offsets []uint64) ([]uint64, error) {
+	var lines []uint64
+	var prev uint64
+	for i, pos := range offsets {
+		// Scan backwards for a newline, but no further than the
+		// previous offset: if there is none in between, the line is
+		// the same one.
+		floor := uint64(0)
+		if i > 0 {
+			floor = prev
+		}
+		start := floor
+		found := i == 0
+		iter := s.idx.text.ReverseIterate(floor, pos)
+		for iter.Next() {
+			if iter.Symbol() == '\n' {
+				start = iter.Index() + 1
+				found = true
+				break
+			}
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+		if found && (len(lines) == 0 || start != lines[len(lines)-1]) {
+			lines = append(lines, start)
+		}
+		prev = pos
+	}
+	return lines, nil
+}
+
+func (s *regexpSearch) readLine(start uint64) ([]byte, error) {
+	var line []byte
+	iter := s.idx.text.Iterate(start, s.idx.text.Len())
+	for iter.Next() && iter.Symbol() != '\n' {
+		line = append(line, byte(iter.Symbol()))
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return line, nil
+}
+
+func intersectSorted(a, b []uint64) []uint64 {
+	var out []uint64
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+func unionSorted(a, b []uint64) []uint64 {
+	out := make([]uint64, 0, len(a)+len(b))
+	out = append(out, a...)
+	out = append(out, b...)
+	sort.Sort(byU64(out))
+	n := 0
+	for i, x := range out {
+		if i == 0 || x != out[n-1] {
+			out[n] = x
+			n++
+		}
+	}
+	return out[:n]
+}
@@ -0,0 +1,118 @@
+package suffixarray
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func NaiveSearchRegexp(text string, re *regexp.Regexp) []Match {
+	var out []Match
+	start := 0
+	for _, line := range strings.Split(text, "\n") {
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			out = append(out, Match{uint64(start + loc[0]), uint64(loc[1] - loc[0]), 0})
+		}
+		start += len(line) + 1
+	}
+	return out
+}
+
+func TestSearchRegexp(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		idx, err := BuildIndex(NewTextFromString(sampleText, opts...), opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildIndex: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for i, expr := range []string{
+			`odio`,
+			`dolor (sit|in)`,
+			`(?i)phasellus`,
+			`[Pp]hasellus nec`,
+			`^Lorem`,
+			`\.$`,
+			`am.t`,
+			`.*`,
+			`x+y`,
+			`\bnec\b`,
+			`a{2,}`,
+			`sit amet,\s+\w+`,
+			`zzz|qqq`,
+			`(ab|cd)(ef|gh)[0-9]`,
+			`(?s)dolor.*sit`,
+		} {
+			re := regexp.MustCompile(expr)
+			checkSearchRegexp(t, cfg.Name, i, idx, re)
+		}
+
+		for i, expr := range []string{
+			`dolor (sit|sit amet)`,
+			`a|am|ame|amet`,
+			`[[:upper:]][[:lower:]]+ nec`,
+			`^Lorem`,
+		} {
+			re := regexp.MustCompilePOSIX(expr)
+			checkSearchRegexp(t, cfg.Name+"/posix", i, idx, re)
+		}
+
+		if err := idx.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
+
+func TestSearchRegexp_LargeAlphabet(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		text, err := NewText(512, extendOptions(opts, NumValues(uint64(len(sampleText))))...)
+		if err != nil {
+			t.Errorf("[%s] NewText: error: %v", cfg.Name, err)
+			continue
+		}
+		iter := text.Iterate(0, text.Len())
+		for iter.Next() {
+			iter.SetSymbol(uint64(sampleText[iter.Index()]))
+		}
+		if err := iter.Close(); err != nil {
+			t.Errorf("[%s] Iterate: error: %v", cfg.Name, err)
+			text.Close()
+			continue
+		}
+
+		idx, err := BuildIndex(text, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildIndex: error: %v", cfg.Name, err)
+			continue
+		}
+
+		if _, err := SearchRegexp(idx, regexp.MustCompile(`odio`)); err == nil {
+			t.Errorf("[%s] SearchRegexp: expected error for alphabet size 512", cfg.Name)
+		}
+
+		if err := idx.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
+
+func checkSearchRegexp(t *testing.T, name string, i int, idx *Index, re *regexp.Regexp) {
+	matches, err := SearchRegexp(idx, re)
+	if err != nil {
+		t.Errorf("[%s/%03d] SearchRegexp %q: error: %v", name, i, re, err)
+		return
+	}
+
+	expected := fmt.Sprintf("%v", NaiveSearchRegexp(sampleText, re))
+	actual := fmt.Sprintf("%v", matches)
+	if expected != actual {
+		t.Errorf("[%s/%03d] SearchRegexp %q: expected %s, got %s", name, i, re, expected, actual)
+	}
+}
@@ -0,0 +1,192 @@
+package suffixarray
+
+import (
+	"math/bits"
+)
+
+// rmqBlockSize is the number of values in each block of a rangeMinimum.
+const rmqBlockSize = 64
+
+// rangeMinimum answers range minimum queries over a Storage, returning the
+// index of the smallest value within any range of indices.
+//
+// The values are divided into blocks of rmqBlockSize, and a sparse table
+// holds, for every run of 2^k blocks, the index of its minimum.  A query
+// scans at most two partial blocks and looks up two overlapping runs of
+// whole blocks, so it takes O(rmqBlockSize) time using O(n/rmqBlockSize *
+// log n) space.  Queries only call ValueAt, so they are safe to run
+// concurrently.
+//
+type rangeMinimum struct {
+	values Storage
+	table  []Storage
+}
+
+// buildRangeMinimum constructs a rangeMinimum over values.  The values must
+// not be modified while the rangeMinimum is in use.
+func buildRangeMinimum(values Storage, opts []Option) (*rangeMinimum, error) {
+	n := values.Len()
+	numBlocks := (n + rmqBlockSize - 1) / rmqBlockSize
+	rm := &rangeMinimum{values: values}
+	if n == 0 {
+		return rm, nil
+	}
+
+	tableOpts := func(length uint64) []Option {
+		return extendOptions(
+			opts,
+			NumValues(length),
+			MaxValue(n-1),
+			WithFile(nil))
+	}
+
+	level, err := makeStorage(tableOpts(numBlocks))
+	if err != nil {
+		return nil, err
+	}
+	rm.table = append(rm.table, level)
+
+	iter := values.Iterate(0, n)
+	var best, bestValue uint64
+	for iter.Next() {
+		index, value := iter.Index(), iter.Value()
+		if index%rmqBlockSize == 0 || value < bestValue {
+			best, bestValue = index, value
+		}
+		if index%rmqBlockSize == rmqBlockSize-1 || index == n-1 {
+			if err = level.SetValueAt(index/rmqBlockSize, best); err != nil {
+				break
+			}
+		}
+	}
+	if err2 := iter.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		rm.Close()
+		return nil, err
+	}
+
+	for width := uint64(2); width <= numBlocks; width *= 2 {
+		prev := rm.table[len(rm.table)-1]
+		level, err := makeStorage(tableOpts(numBlocks - width + 1))
+		if err != nil {
+			rm.Close()
+			return nil, err
+		}
+		rm.table = append(rm.table, level)
+
+		for j := uint64(0); j < level.Len(); j++ {
+			best, err := rm.minOf(prev, j, j+width/2)
+			if err == nil {
+				err = level.SetValueAt(j, best)
+			}
+			if err != nil {
+				rm.Close()
+				return nil, err
+			}
+		}
+	}
+	return rm, nil
+}
+
+// minOf returns whichever of the indices stored at slots i and j of a table
+// level refers to the smaller value, preferring the first on a tie.
+func (rm *rangeMinimum) minOf(level Storage, i, j uint64) (uint64, error) {
+	a, err := level.ValueAt(i)
+	if err != nil {
+		return 0, err
+	}
+	b, err := level.ValueAt(j)
+	if err != nil {
+		return 0, err
+	}
+	return rm.better(a, b)
+}
+
+// better returns whichever of the indices a and b refers to the smaller
+// value, preferring a on a tie.
+func (rm *rangeMinimum) better(a, b uint64) (uint64, error) {
+	va, err := rm.values.ValueAt(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := rm.values.ValueAt(b)
+	if err != nil {
+		return 0, err
+	}
+	if vb < va {
+		return b, nil
+	}
+	return a, nil
+}
+
+// scan returns the index of the smallest value in [lo, hi) by examining each
+// one.  The range must not be empty.
+func (rm *rangeMinimum) scan(lo, hi uint64) (uint64, uint64, error) {
+	best, bestValue := lo, uint64(0)
+	for i := lo; i < hi; i++ {
+		value, err := rm.values.ValueAt(i)
+		if err != nil {
+			return 0, 0, err
+		}
+		if i == lo || value < bestValue {
+			best, bestValue = i, value
+		}
+	}
+	return best, bestValue, nil
+}
+
+// ArgMin returns the index of the smallest value in [lo, hi), which must not
+// be empty.  On a tie, any of the smallest values may be chosen.
+func (rm *rangeMinimum) ArgMin(lo, hi uint64) (uint64, error) {
+	firstBlock := lo / rmqBlockSize
+	lastBlock := (hi - 1) / rmqBlockSize
+	if lastBlock-firstBlock <= 1 {
+		best, _, err := rm.scan(lo, hi)
+		return best, err
+	}
+
+	best, _, err := rm.scan(lo, (firstBlock+1)*rmqBlockSize)
+	if err != nil {
+		return 0, err
+	}
+	last, _, err := rm.scan(lastBlock*rmqBlockSize, hi)
+	if err != nil {
+		return 0, err
+	}
+	if best, err = rm.better(best, last); err != nil {
+		return 0, err
+	}
+
+	// Cover the whole blocks between with two runs of 2^k blocks.
+	width := lastBlock - firstBlock - 1
+	k := bits.Len64(width) - 1
+	level := rm.table[k]
+	middle, err := rm.minOf(level, firstBlock+1, lastBlock-uint64(1)<<uint(k))
+	if err != nil {
+		return 0, err
+	}
+	return rm.better(best, middle)
+}
+
+// Min returns the smallest value in [lo, hi), which must not be empty.
+func (rm *rangeMinimum) Min(lo, hi uint64) (uint64, error) {
+	best, err := rm.ArgMin(lo, hi)
+	if err != nil {
+		return 0, err
+	}
+	return rm.values.ValueAt(best)
+}
+
+// Close frees the resources used by the rangeMinimum, but not the values.
+func (rm *rangeMinimum) Close() error {
+	var finalError error
+	for _, level := range rm.table {
+		if err := level.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	rm.table = nil
+	return finalError
+}
@@ -0,0 +1,60 @@
+package suffixarray
+
+import (
+	"testing"
+)
+
+func TestRangeMinimum(t *testing.T) {
+	var values []uint64
+	for i := uint64(0); i < 1000; i++ {
+		values = append(values, (i*7919)%997)
+	}
+
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		ba, err := makeStorage(extendOptions(opts, NumValues(uint64(len(values))), MaxValue(1000)))
+		if err != nil {
+			t.Errorf("[%s] makeStorage: error: %v", cfg.Name, err)
+			continue
+		}
+		iter := ba.Iterate(0, ba.Len())
+		for iter.Next() {
+			iter.SetValue(values[iter.Index()])
+		}
+		if err := iter.Close(); err != nil {
+			t.Errorf("[%s] SetValue: error: %v", cfg.Name, err)
+			continue
+		}
+
+		rm, err := buildRangeMinimum(ba, opts)
+		if err != nil {
+			t.Errorf("[%s] buildRangeMinimum: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for _, q := range [][2]uint64{
+			{0, 1}, {0, 1000}, {5, 70}, {63, 65}, {64, 128}, {1, 999}, {100, 400}, {999, 1000}, {127, 320},
+		} {
+			lo, hi := q[0], q[1]
+			expected := values[lo]
+			for _, value := range values[lo:hi] {
+				if value < expected {
+					expected = value
+				}
+			}
+			index, err := rm.ArgMin(lo, hi)
+			if err != nil || index < lo || index >= hi || values[index] != expected {
+				t.Errorf("[%s] ArgMin %v: expected a minimum of %d, got index %d, %v", cfg.Name, q, expected, index, err)
+			}
+		}
+
+		if err := rm.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+		if err := ba.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
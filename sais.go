@@ -1,9 +1,5 @@
 package suffixarray
 
-import (
-	bigarray "github.com/team-spectre/go-bigarray"
-)
-
 const placeholder = ^uint64(0)
 
 func guessLMSSort(text *Text, typeMap *TypeMap, bucketSizes []uint64, opts []Option) (*SuffixArray, error) {
@@ -179,7 +175,7 @@ func induceSortR(text *Text, typeMap *TypeMap, bucketSizes []uint64, sa *SuffixA
 	return nil
 }
 
-func summarize(text *Text, typeMap *TypeMap, sa *SuffixArray, opts []Option) (*Text, bigarray.BigArray, error) {
+func summarize(text *Text, typeMap *TypeMap, sa *SuffixArray, opts []Option) (*Text, Storage, error) {
 	lmsOpts := extendOptions(
 		opts,
 		NumValues(text.Len()+1),
@@ -251,7 +247,7 @@ func summarize(text *Text, typeMap *TypeMap, sa *SuffixArray, opts []Option) (*T
 		BytesPerValue(8),
 		NumValues(lmsNames.Len()))
 
-	summarySuffixOffsets, err := makeBigArray(opts)
+	summarySuffixOffsets, err := makeStorage(opts)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -335,7 +331,7 @@ func buildSummarySuffixArray(summaryText *Text, opts []Option) (*SuffixArray, er
 	return BuildSuffixArray(summaryText, opts...)
 }
 
-func exactLMSSort(text *Text, typeMap *TypeMap, bucketSizes []uint64, summarySuffixArray *SuffixArray, summarySuffixOffsets bigarray.BigArray, opts []Option) (*SuffixArray, error) {
+func exactLMSSort(text *Text, typeMap *TypeMap, bucketSizes []uint64, summarySuffixArray *SuffixArray, summarySuffixOffsets Storage, opts []Option) (*SuffixArray, error) {
 	opts = extendOptions(
 		opts,
 		NumValues(text.Len()+1),
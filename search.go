@@ -8,176 +8,341 @@ import (
 	bigarray "github.com/team-spectre/go-bigarray"
 )
 
-type compareResult byte
-
-const (
-	_ compareResult = iota
-	goLeft
-	goRight
-	stopHere
-)
-
 type searchState struct {
 	text   *Text
-	sa     *SuffixArray
-	lcplr  bigarray.BigArray
-	phrase string
-	index  uint64
-	height uint64
-	lo     uint64
-	hi     uint64
+	sa     SuffixArrayReader
+	lcplr  Storage
+	phrase []uint64
+
+	// upper selects which end of the matches bound() finds.  Suffixes
+	// which begin with the phrase sort after it when upper is false, and
+	// before it when upper is true.
+	upper bool
 }
 
-func (state *searchState) compare(where uint64) (compareResult, uint64, error) {
-	if where < state.lo || where > state.hi {
-		panic("BUG")
-	}
-
+// compare compares the phrase against the suffix SA[where], skipping the first
+// height symbols, which the caller knows that they share.  Returns the number
+// of symbols which they share, and whether the phrase sorts before the suffix.
+//
+// The text is read through random access, like the suffix array, so that
+// searches may run concurrently, as they do in an IncrementalIndex.
+func (state *searchState) compare(where, height uint64) (uint64, bool, error) {
 	pos, err := state.sa.PositionAt(where)
 	if err != nil {
-		return 0, 0, fmt.Errorf("[%d/%d] sa.PositionAt %d: %v", state.lo, state.hi, where, err)
+		return 0, false, fmt.Errorf("sa.PositionAt %d: %v", where, err)
 	}
 	if gDebug {
-		log.Printf("debug: [%d/%d] height=%d sa.PositionAt[%d]=%d", state.lo, state.hi, state.height, where, pos)
+		log.Printf("debug: height=%d sa.PositionAt[%d]=%d", height, where, pos)
 	}
 
-	i := state.height
-	iter := state.text.Iterate(pos+state.height, state.text.Len())
-	result := compareResult(0)
-	for iter.Next() && i < uint64(len(state.phrase)) {
-		ch0 := uint64(state.phrase[i])
-		ch1 := iter.Symbol()
-		if ch0 < ch1 {
-			result = goLeft
+	i := height
+	before := !state.upper
+	for i < uint64(len(state.phrase)) {
+		if pos+i >= state.text.Len() {
+			before = false
 			break
 		}
-		if ch0 > ch1 {
-			result = goRight
+		ch0 := state.phrase[i]
+		ch1, err := state.text.SymbolAt(pos + i)
+		if err != nil {
+			return 0, false, err
+		}
+		if ch0 != ch1 {
+			before = ch0 < ch1
 			break
 		}
 		i++
 	}
-	err = iter.Close()
-	if err != nil {
-		return 0, 0, err
+	return i, before, nil
+}
+
+// bound returns the index of the first suffix which sorts after the phrase,
+// and the number of symbols which the phrase shares with that suffix, or 0 if
+// there is none.
+//
+// This is the search described by BuildLCPLRArray.  Each step keeps
+// SA[lo] < P < SA[hi], where SA[n] is imaginary and sorts after every phrase,
+// and llcp and rlcp are the numbers of symbols which P shares with SA[lo] and
+// SA[hi].  The (lo, hi) ranges are the nodes of the LCP-LR array.
+//
+func (state *searchState) bound() (uint64, uint64, error) {
+	matched, before, err := state.compare(0, 0)
+	if err != nil || before {
+		return 0, matched, err
 	}
 
-	if result == 0 {
-		if i < uint64(len(state.phrase)) {
-			result = goRight
-		} else {
-			result = stopHere
+	lo, hi := uint64(0), state.sa.Len()
+	llcp, rlcp := matched, uint64(0)
+	index := uint64(0)
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+
+		// Look up lcp(SA[lo], SA[mid]) if the phrase shares more with
+		// SA[lo], or lcp(SA[mid], SA[hi]) if it shares more with SA[hi].
+		height, child, towardLo := llcp, 2*index+1, true
+		if rlcp > llcp {
+			height, child, towardLo = rlcp, 2*index+2, false
 		}
-	}
-	return result, pos, nil
-}
 
-// Search performs a binary search on the suffix array, using the provided
-// LCP-LR array to reduce the time requirements to O(m + log n).  Returns the
-// list of offsets into the text which begin with the given phrase.
-func Search(text *Text, sa *SuffixArray, lcplr bigarray.BigArray, phrase string) ([]uint64, error) {
-	state := searchState{
-		text:   text,
-		sa:     sa,
-		lcplr:  lcplr,
-		phrase: phrase,
-		index:  0,
-		lo:     0,
-		hi:     sa.Len() - 1,
-	}
+		var shared uint64
+		if state.lcplr != nil {
+			shared, err = state.lcplr.ValueAt(child)
+			if err != nil {
+				return 0, 0, err
+			}
+		} else {
+			// Without an LCP-LR array, SA[mid] is only known to
+			// share the lesser of llcp and rlcp with the phrase.
+			if llcp < rlcp {
+				height = llcp
+			} else {
+				height = rlcp
+			}
+			shared = height
+		}
 
-	var where, pos uint64
-	var cmp compareResult
-	var err error
-	found := false
-	for state.hi >= (state.lo+3) && !found {
-		where = state.lo + (state.hi-state.lo)/2
+		var goLeft bool
+		switch {
+		case shared > height:
+			// SA[mid] agrees with the near bound where the phrase
+			// does not, so it lies on the same side of the phrase.
+			matched, goLeft = height, !towardLo
+
+		case shared < height:
+			// SA[mid] differs from the near bound where the phrase
+			// does not, so it lies on the far side of the phrase.
+			matched, goLeft = shared, towardLo
+
+		default:
+			matched, goLeft, err = state.compare(mid, height)
+			if err != nil {
+				return 0, 0, err
+			}
+		}
 
-		state.height, err = lcplr.ValueAt(state.index)
-		if err != nil {
-			return nil, err
+		if goLeft {
+			hi, rlcp = mid, matched
+			index = 2*index + 1
+		} else {
+			lo, llcp = mid, matched
+			index = 2*index + 2
 		}
+	}
+	return hi, rlcp, nil
+}
 
-		cmp, pos, err = state.compare(where)
+// boundIn is like bound, but only searches [lo, hi), every suffix of which
+// the caller knows to begin with the first height symbols of the phrase.
+// Rather than consulting the LCP-LR array, it compares each suffix from the
+// lesser of the numbers of symbols which the phrase shares with the suffixes
+// bounding the search so far, since every suffix between them shares at least
+// that many.  Also returns the number of symbols which the phrase shares with
+// the suffix found, or height if the search found hi.
+func (state *searchState) boundIn(lo, hi, height uint64) (uint64, uint64, error) {
+	llcp, rlcp := height, height
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		skip := llcp
+		if rlcp < skip {
+			skip = rlcp
+		}
+		matched, before, err := state.compare(mid, skip)
 		if err != nil {
-			return nil, err
+			return 0, 0, err
+		}
+		if before {
+			hi, rlcp = mid, matched
+		} else {
+			lo, llcp = mid+1, matched
 		}
+	}
+	return hi, rlcp, nil
+}
 
-		switch cmp {
-		case goLeft:
-			state.index = 2*state.index + 1
-			state.hi = where - 1
+// Search performs a binary search on the suffix array, using the provided
+// LCP-LR array to reduce the time requirements to O(m + log n).  Returns the
+// list of offsets into the text which begin with the given phrase.
+//
+// The LCP-LR array may be nil, in which case the search takes O(m log n) time.
+//
+// The suffix array may be a SuffixArray or a CompressedSuffixArray.
+func Search(text *Text, sa SuffixArrayReader, lcplr bigarray.BigArray, phrase string) ([]uint64, error) {
+	return searchSymbols(text, sa, bigArrayToStorage(lcplr), stringToSymbols(phrase))
+}
 
-		case goRight:
-			state.index = 2*state.index + 2
-			state.lo = where + 1
+// SearchSymbols is like Search, but the phrase is given as a list of symbols
+// rather than as a string of bytes.  This allows searching texts whose
+// alphabet is larger than 256.
+func SearchSymbols(text *Text, sa SuffixArrayReader, lcplr bigarray.BigArray, phrase []uint64) ([]uint64, error) {
+	return searchSymbols(text, sa, bigArrayToStorage(lcplr), phrase)
+}
 
-		case stopHere:
-			found = true
-		}
+// searchSymbols is like SearchSymbols, but takes the LCP-LR array as a
+// Storage.
+func searchSymbols(text *Text, sa SuffixArrayReader, lcplr Storage, phrase []uint64) ([]uint64, error) {
+	lo, hi, err := rangeSymbols(text, sa, lcplr, phrase)
+	if err != nil {
+		return nil, err
+	}
+	if lo == hi {
+		return nil, nil
 	}
 
-	for state.hi >= state.lo && !found {
-		where = state.lo
+	results := make([]uint64, 0, hi-lo)
+	iter := sa.Iterate(lo, hi)
+	for iter.Next() {
+		results = append(results, iter.Position())
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
 
-		cmp, pos, err = state.compare(where)
-		if err != nil {
-			return nil, err
-		}
+	sort.Sort(byU64(results))
+	return results, nil
+}
 
-		switch cmp {
-		case goLeft:
-			state.hi = where - 1
+// Range performs the same binary search as Search, but returns the half-open
+// range of suffix array indices [lo, hi) whose suffixes begin with the given
+// phrase, rather than the text offsets themselves.  If there are no matches,
+// then lo == hi.
+//
+// Both ends of the range are located by binary search, so the time
+// requirements are O(m + log n) regardless of the number of matches.
+func Range(text *Text, sa SuffixArrayReader, lcplr bigarray.BigArray, phrase string) (uint64, uint64, error) {
+	return rangeSymbols(text, sa, bigArrayToStorage(lcplr), stringToSymbols(phrase))
+}
 
-		case goRight:
-			state.lo = where + 1
+// RangeSymbols is like Range, but the phrase is given as a list of symbols.
+func RangeSymbols(text *Text, sa SuffixArrayReader, lcplr bigarray.BigArray, phrase []uint64) (uint64, uint64, error) {
+	return rangeSymbols(text, sa, bigArrayToStorage(lcplr), phrase)
+}
 
-		case stopHere:
-			found = true
-		}
+// rangeSymbols is like RangeSymbols, but takes the LCP-LR array as a Storage.
+func rangeSymbols(text *Text, sa SuffixArrayReader, lcplr Storage, phrase []uint64) (uint64, uint64, error) {
+	state := searchState{
+		text:   text,
+		sa:     sa,
+		lcplr:  lcplr,
+		phrase: phrase,
 	}
 
-	if !found {
-		return nil, nil
+	lo, _, err := state.bound()
+	if err != nil {
+		return 0, 0, err
 	}
 
-	results := make([]uint64, 0, 16)
-	results = append(results, pos)
+	state.upper = true
+	hi, _, err := state.bound()
+	if err != nil {
+		return 0, 0, err
+	}
+	return lo, hi, nil
+}
 
-	lowerBound := where
-	for lowerBound > state.lo {
-		lowerBound--
+// Count returns the number of offsets in the text which begin with the given
+// phrase.  It is equivalent to len(Search(...)), but does not need to visit
+// each match.
+func Count(text *Text, sa SuffixArrayReader, lcplr bigarray.BigArray, phrase string) (uint64, error) {
+	lo, hi, err := Range(text, sa, lcplr, phrase)
+	return hi - lo, err
+}
 
-		cmp, pos, err = state.compare(lowerBound)
+// searchIndices returns the smallest index in [lo, hi) for which pred is
+// true, or hi if there is none.  Like sort.Search, it assumes that pred is
+// false for some prefix of the range and true for the remainder.
+func searchIndices(lo, hi uint64, pred func(uint64) (bool, error)) (uint64, error) {
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		ok, err := pred(mid)
 		if err != nil {
-			return nil, err
+			return 0, err
 		}
-
-		if cmp != stopHere {
-			break
+		if ok {
+			hi = mid
+		} else {
+			lo = mid + 1
 		}
+	}
+	return lo, nil
+}
 
-		results = append(results, pos)
+// symbolAtDepth returns the symbol at the given depth of the suffix SA[index],
+// or false if the suffix is not long enough to have one.
+func symbolAtDepth(text *Text, sa *SuffixArray, index uint64, depth uint64) (uint64, bool, error) {
+	pos, err := sa.PositionAt(index)
+	if err != nil {
+		return 0, false, err
+	}
+	if pos+depth >= text.Len() {
+		return 0, false, nil
 	}
+	symbol, err := text.SymbolAt(pos + depth)
+	return symbol, true, err
+}
 
-	upperBound := where
-	for upperBound < state.hi {
-		upperBound++
+// narrowRange returns the subrange of [lo, hi) whose suffixes have the given
+// symbol at the given depth.  Every suffix in [lo, hi) must share the same
+// first depth symbols, so that they are sorted by their symbol at that depth.
+func narrowRange(text *Text, sa *SuffixArray, lo, hi, depth, symbol uint64) (uint64, uint64, error) {
+	return narrowRangeSet(text, sa, lo, hi, depth, symbol, symbol)
+}
 
-		cmp, pos, err = state.compare(upperBound)
-		if err != nil {
-			return nil, err
-		}
+// narrowRangeSet is like narrowRange, but selects the suffixes whose symbol
+// at the given depth lies anywhere in [first, last].
+func narrowRangeSet(text *Text, sa *SuffixArray, lo, hi, depth, first, last uint64) (uint64, uint64, error) {
+	lo, err := searchIndices(lo, hi, func(index uint64) (bool, error) {
+		ch, ok, err := symbolAtDepth(text, sa, index, depth)
+		return ok && ch >= first, err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	hi, err = searchIndices(lo, hi, func(index uint64) (bool, error) {
+		ch, ok, err := symbolAtDepth(text, sa, index, depth)
+		return ok && ch > last, err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return lo, hi, nil
+}
 
-		if cmp != stopHere {
-			break
-		}
+// firstChildRange returns the first subrange of [lo, hi) whose suffixes all
+// have the same symbol at the given depth, along with that symbol.  Suffixes
+// which end before the given depth sort first, and are skipped.  Returns false
+// if no suffix in the range is long enough.  The same conditions apply as for
+// narrowRange; by repeating the call on [childHi, hi), the caller can visit
+// every child of the range in lexicographic order.
+func firstChildRange(text *Text, sa *SuffixArray, lo, hi, depth uint64) (symbol, childLo, childHi uint64, ok bool, err error) {
+	childLo, err = searchIndices(lo, hi, func(index uint64) (bool, error) {
+		_, ok, err := symbolAtDepth(text, sa, index, depth)
+		return ok, err
+	})
+	if err != nil || childLo == hi {
+		return 0, 0, 0, false, err
+	}
 
-		results = append(results, pos)
+	symbol, _, err = symbolAtDepth(text, sa, childLo, depth)
+	if err != nil {
+		return 0, 0, 0, false, err
 	}
 
-	sort.Sort(byU64(results))
-	return results, nil
+	childHi, err = searchIndices(childLo, hi, func(index uint64) (bool, error) {
+		ch, _, err := symbolAtDepth(text, sa, index, depth)
+		return ch > symbol, err
+	})
+	if err != nil {
+		return 0, 0, 0, false, err
+	}
+	return symbol, childLo, childHi, true, nil
+}
+
+func stringToSymbols(str string) []uint64 {
+	out := make([]uint64, len(str))
+	for i := 0; i < len(str); i++ {
+		out[i] = uint64(str[i])
+	}
+	return out
 }
 
 type byU64 []uint64
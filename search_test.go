@@ -2,7 +2,10 @@ package suffixarray
 
 import (
 	"fmt"
+	"strings"
 	"testing"
+
+	bigarray "github.com/team-spectre/go-bigarray"
 )
 
 const sampleText = `
@@ -85,3 +88,126 @@ func TestSearch_EndToEnd(t *testing.T) {
 		}
 	}
 }
+
+func NaiveSearch(text, phrase string) []uint64 {
+	var out []uint64
+	for i := 0; i+len(phrase) <= len(text); i++ {
+		if strings.HasPrefix(text[i:], phrase) {
+			out = append(out, uint64(i))
+		}
+	}
+	return out
+}
+
+func TestRange(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		text := NewTextFromString(sampleText, opts...)
+
+		sa, err := BuildSuffixArray(text, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildSuffixArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		lcp, err := BuildLCPArray(text, sa, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildLCPArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		lcplr, err := BuildLCPLRArray(lcp, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildLCPLRArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for _, phrase := range []string{searchPhrase, "Lorem", "lorem", "a", " ", "\n", "zzz", "Phasellus nec", "mus.\n"} {
+			expected := uint64(len(NaiveSearch(sampleText, phrase)))
+
+			lo, hi, err := Range(text, sa, lcplr, phrase)
+			if err != nil {
+				t.Errorf("[%s] Range %q: error: %v", cfg.Name, phrase, err)
+				continue
+			}
+			if hi-lo != expected {
+				t.Errorf("[%s] Range %q: expected %d matches, got [%d, %d)", cfg.Name, phrase, expected, lo, hi)
+			}
+
+			count, err := Count(text, sa, lcplr, phrase)
+			if err != nil {
+				t.Errorf("[%s] Count %q: error: %v", cfg.Name, phrase, err)
+				continue
+			}
+			if count != expected {
+				t.Errorf("[%s] Count %q: expected %d, got %d", cfg.Name, phrase, expected, count)
+			}
+		}
+	}
+}
+
+func TestSearch_AbsentPhrases(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		text := NewTextFromString(sampleText, opts...)
+
+		sa, err := BuildSuffixArray(text, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildSuffixArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		lcp, err := BuildLCPArray(text, sa, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildLCPArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		lcplr, err := BuildLCPLRArray(lcp, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildLCPLRArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		// Phrases which share a long prefix with many suffixes but
+		// occur rarely or nowhere, along with the phrases they miss.
+		phrases := []string{"t amx", "t amet", "Phasellus nex", "\n\nz", "zzz", "\x00"}
+		for i := 0; i+4 < len(sampleText); i += 7 {
+			phrases = append(phrases, sampleText[i:i+4], sampleText[i:i+4]+"x", sampleText[i:i+3]+" ", sampleText[i:i+2]+"e")
+		}
+
+		for _, phrase := range phrases {
+			naive := NaiveSearch(sampleText, phrase)
+			expected := fmt.Sprintf("%v", naive)
+
+			offsets, err := Search(text, sa, lcplr, phrase)
+			if err != nil {
+				t.Errorf("[%s] Search %q: error: %v", cfg.Name, phrase, err)
+				continue
+			}
+			if actual := fmt.Sprintf("%v", offsets); actual != expected {
+				t.Errorf("[%s] Search %q: expected %s, got %s", cfg.Name, phrase, expected, actual)
+			}
+
+			for _, table := range []bigarray.BigArray{lcplr, nil} {
+				lo, hi, err := Range(text, sa, table, phrase)
+				if err != nil {
+					t.Errorf("[%s] Range %q: error: %v", cfg.Name, phrase, err)
+					continue
+				}
+				if hi-lo != uint64(len(naive)) {
+					t.Errorf("[%s] Range %q: expected %d matches, got [%d, %d)", cfg.Name, phrase, len(naive), lo, hi)
+				}
+
+				count, err := Count(text, sa, table, phrase)
+				if err != nil || count != uint64(len(naive)) {
+					t.Errorf("[%s] Count %q: expected %d, got %d, %v", cfg.Name, phrase, len(naive), count, err)
+				}
+			}
+		}
+	}
+}
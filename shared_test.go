@@ -4,45 +4,43 @@ import (
 	"strconv"
 	"strings"
 	"sync"
-
-	bigarray "github.com/team-spectre/go-bigarray"
 )
 
 const banana = `banana`
 const bananaTM = `[L@L@LL@]`
 const bananaSA = `[6 5 3 1 0 4 2]`
 const bananaLCP = `[. 0 1 3 0 0 2]`
-const bananaLCPLR = `[0 0 0]`
+const bananaLCPLR = `[0 0 0 0 1 0 0 . . 1 3 0 0 2 0]`
 
 const banana2 = `banana.banana`
 const banana2TM = `[L@L@LL@L@L@LL@]`
 const banana2SA = `[13 6 12 5 10 3 8 1 7 0 11 4 9 2]`
 const banana2LCP = `[. 0 0 1 1 3 3 5 0 6 0 2 2 4]`
-const banana2LCPLR = `[0 0 0 0 1 0 2]`
+const banana2LCPLR = `[0 0 0 0 1 0 0 0 0 1 3 0 0 2 0 . . 0 1 1 3 3 5 . . 6 0 2 2 4 0]`
 
 const cabbage = `cabbage`
 const cabbageTM = `[L@LL@LL@]`
 const cabbageSA = `[7 1 4 3 2 0 6 5]`
 const cabbageLCP = `[. 0 1 0 1 0 0 0]`
-const cabbageLCPLR = `[0 0 0]`
+const cabbageLCPLR = `[0 0 0 0 0 0 0 0 1 0 1 0 0 0 0]`
 
 const loremIpsum = `Lorem ipsum dolor sit amet, consectetur adipiscing elit.`
 const loremIpsumTM = `[SSL@L@SSSLL@SL@SL@L@L@SL@LL@SL@LL@L@SLL@SSSL@L@SLL@SL@LL@]`
 const loremIpsumSA = `[56 39 21 27 11 50 5 17 26 55 0 40 22 46 28 33 41 12 32 51 3 24 35 49 47 42 6 44 19 53 52 14 10 4 23 48 30 13 29 15 1 43 7 38 16 2 45 31 18 8 20 25 54 34 36 9 37]`
 const loremIpsumLCP = `[. 0 2 1 1 1 1 1 0 0 0 0 1 0 1 1 0 1 0 1 1 1 2 0 0 1 2 1 1 2 0 1 0 2 1 0 1 0 1 1 2 0 1 0 2 1 0 1 1 1 0 1 1 1 1 0 1]`
-const loremIpsumLCPLR = `[0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 1 0 0 1 0 0 1 0 1 0 0 2 1 1 0]`
+const loremIpsumLCPLR = `[0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 1 0 0 0 0 0 1 0 0 0 0 0 0 0 0 0 1 1 1 0 0 0 0 1 0 0 1 2 0 1 1 2 0 0 0 1 0 1 0 0 1 0 1 0 1 0 0 . . 2 1 1 1 1 1 . . 0 0 0 1 0 1 . . 0 1 0 1 1 1 . . 0 0 1 2 1 1 . . 0 1 0 2 1 0 . . 0 1 1 2 0 1 . . 2 1 0 1 1 1 0 1 1 1 1 0 1 0]`
 
 const abcdefgh = `abcdefgh`
 const abcdefghTM = `[SSSSSSSL@]`
 const abcdefghSA = `[8 0 1 2 3 4 5 6 7]`
 const abcdefghLCP = `[. 0 0 0 0 0 0 0 0]`
-const abcdefghLCPLR = `[0 0 0]`
+const abcdefghLCPLR = `[0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 . . . . . . . . . . . . . . 0 0]`
 
 const aaaaaaaa = `aaaaaaaa`
 const aaaaaaaaTM = `[LLLLLLLL@]`
 const aaaaaaaaSA = `[8 7 6 5 4 3 2 1 0]`
 const aaaaaaaaLCP = `[. 0 1 2 3 4 5 6 7]`
-const aaaaaaaaLCPLR = `[0 0 5]`
+const aaaaaaaaLCPLR = `[0 0 0 0 2 4 0 0 1 2 3 4 5 6 0 . . . . . . . . . . . . . . 7 0]`
 
 func NewTextFromString(str string, opts ...Option) *Text {
 	opts = extendOptions(
@@ -59,7 +57,7 @@ func NewTextFromString(str string, opts ...Option) *Text {
 	return text
 }
 
-func NewBigArrayFromString(str string, opts ...Option) bigarray.BigArray {
+func NewStorageFromString(str string, opts ...Option) Storage {
 	pieces := strings.Split(str[1:len(str)-1], " ")
 
 	opts = extendOptions(
@@ -67,7 +65,7 @@ func NewBigArrayFromString(str string, opts ...Option) bigarray.BigArray {
 		NumValues(uint64(len(pieces))),
 		BytesPerValue(8))
 
-	ba, err := makeBigArray(opts)
+	ba, err := makeStorage(opts)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,298 @@
+package suffixarray
+
+import (
+	"fmt"
+)
+
+// PositionIterator yields a sequence of text offsets.
+//
+// The basic usage pattern is:
+//
+//   iter := EveryKth(text, 8)
+//   for iter.Next() {
+//     ... // call Position()
+//   }
+//   err := iter.Close()
+//   if err != nil {
+//     ... // handle error
+//   }
+//
+// An Iterator over a SuffixArray is also a PositionIterator.
+//
+type PositionIterator interface {
+	// Next advances the iterator to the next offset and returns true, or
+	// returns false if the end of the iteration has been reached or if an
+	// error has occurred.
+	Next() bool
+
+	// Position returns the current text offset.
+	Position() uint64
+
+	// Err returns the error which caused Next() to return false.
+	Err() error
+
+	// Close frees the resources used by the iterator.
+	Close() error
+}
+
+var _ PositionIterator = (*Iterator)(nil)
+
+type strideIterator struct {
+	next   uint64
+	pos    uint64
+	stride uint64
+	limit  uint64
+}
+
+// EveryKth returns a PositionIterator which yields the offsets 0, k, 2k, ...
+// up to the length of the text.
+func EveryKth(text *Text, k uint64) PositionIterator {
+	if k == 0 {
+		panic("EveryKth: k must be positive")
+	}
+	return &strideIterator{stride: k, limit: text.Len()}
+}
+
+func (iter *strideIterator) Next() bool {
+	if iter.next >= iter.limit {
+		return false
+	}
+	iter.pos = iter.next
+	iter.next += iter.stride
+	return true
+}
+
+func (iter *strideIterator) Position() uint64 { return iter.pos }
+func (iter *strideIterator) Err() error       { return nil }
+func (iter *strideIterator) Close() error     { return nil }
+
+type predicateIterator struct {
+	impl *TextIterator
+	pred func(uint64, uint64, uint64) bool
+	prev uint64
+}
+
+// PositionsWhere returns a PositionIterator which yields each offset of the
+// text for which pred returns true.  The predicate receives the offset, the
+// symbol preceding the offset (or 2^64-1 at offset 0), and the symbol at the
+// offset, which is enough to select e.g. word or line starts.
+func PositionsWhere(text *Text, pred func(index uint64, prev uint64, symbol uint64) bool) PositionIterator {
+	return &predicateIterator{
+		impl: text.Iterate(0, text.Len()),
+		pred: pred,
+		prev: placeholder,
+	}
+}
+
+func (iter *predicateIterator) Next() bool {
+	for iter.impl.Next() {
+		prev := iter.prev
+		iter.prev = iter.impl.Symbol()
+		if iter.pred(iter.impl.Index(), prev, iter.impl.Symbol()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (iter *predicateIterator) Position() uint64 { return iter.impl.Index() }
+func (iter *predicateIterator) Err() error       { return iter.impl.Err() }
+func (iter *predicateIterator) Close() error     { return iter.impl.Close() }
+
+// BuildSparseSuffixArray constructs a sparse suffix array, which contains
+// only those suffixes of the text which begin at the offsets yielded by
+// positions.  The iterator is consumed and closed.
+//
+// The result is an ordinary SuffixArray: SA[0] is still the empty suffix at
+// the end of the text, and the remaining entries are the selected offsets in
+// lexicographic order of their suffixes.  Only occurrences beginning at a
+// selected offset can be found by searching it; see SearchSparse.
+//
+// Only the k selected suffixes are sorted, by multikey quicksort [1], so the
+// only temporary is a bitmap of the selected offsets.  The time requirements
+// are O(k log k + D), where D is the total length of the prefixes which tell
+// the selected suffixes apart.  D is small for natural text, but a text with
+// long repeats, such as "aaaa...", can make it as large as O(kn).
+//
+// Reference:
+//
+//  [1] “Fast Algorithms for Sorting and Searching Strings”,
+//      Jon L. Bentley and Robert Sedgewick.
+//      https://www.cs.princeton.edu/~rs/strings/
+//
+func BuildSparseSuffixArray(text *Text, positions PositionIterator, opts ...Option) (*SuffixArray, error) {
+	markOpts := extendOptions(
+		opts,
+		NumValues(text.Len()),
+		WithFile(nil))
+
+	marks, err := makeBitStorage(markOpts)
+	if err != nil {
+		positions.Close()
+		return nil, err
+	}
+	defer marks.Close()
+
+	count := uint64(0)
+	for positions.Next() {
+		pos := positions.Position()
+		if pos >= text.Len() {
+			positions.Close()
+			return nil, fmt.Errorf("BuildSparseSuffixArray: position %d is out of range for text of length %d", pos, text.Len())
+		}
+		if err := marks.SetValueAt(pos, 1); err != nil {
+			positions.Close()
+			return nil, err
+		}
+		count++
+	}
+	if err := positions.Close(); err != nil {
+		return nil, err
+	}
+
+	sparseOpts := extendOptions(
+		opts,
+		NumValues(count+1),
+		MaxValue(text.Len()))
+
+	sparse, err := New(sparseOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	needClose := true
+	defer func() {
+		if needClose {
+			sparse.Close()
+		}
+	}()
+
+	sparseIter := sparse.Iterate(0, sparse.Len())
+	if !sparseIter.Next() {
+		sparseIter.Close()
+		return nil, sparseIter.Err()
+	}
+	sparseIter.SetPosition(text.Len())
+
+	actual := uint64(1)
+	err = forEachValue(marks, func(pos uint64, marked uint64) error {
+		if marked == 0 {
+			return nil
+		}
+		if !sparseIter.Next() {
+			return sparseIter.Err()
+		}
+		sparseIter.SetPosition(pos)
+		actual++
+		return nil
+	})
+	if err2 := sparseIter.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Duplicate positions are only marked once.
+	if actual < sparse.Len() {
+		if err := sparse.Truncate(actual); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := sortSuffixes(text, sparse, 1, actual, 0); err != nil {
+		return nil, err
+	}
+
+	needClose = false
+	return sparse, nil
+}
+
+// sortSuffixes sorts the suffixes which begin at the offsets SA[lo:hi], all
+// of which share their first depth symbols, by multikey quicksort.  Each pass
+// partitions the range by the symbol at the given depth, sorting the lesser
+// and greater parts recursively and the equal part at the next depth.
+func sortSuffixes(text *Text, sa *SuffixArray, lo, hi, depth uint64) error {
+	for hi-lo > 1 {
+		pivot, err := sortKey(text, sa, lo+(hi-lo)/2, depth)
+		if err != nil {
+			return err
+		}
+
+		lt, i, gt := lo, lo, hi
+		for i < gt {
+			key, err := sortKey(text, sa, i, depth)
+			if err != nil {
+				return err
+			}
+			switch {
+			case key < pivot:
+				err = swapPositions(sa, lt, i)
+				lt++
+				i++
+			case key > pivot:
+				gt--
+				err = swapPositions(sa, i, gt)
+			default:
+				i++
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := sortSuffixes(text, sa, lo, lt, depth); err != nil {
+			return err
+		}
+		if err := sortSuffixes(text, sa, gt, hi, depth); err != nil {
+			return err
+		}
+
+		// Suffixes which end at this depth are all the same suffix.
+		if pivot == 0 {
+			break
+		}
+		lo, hi, depth = lt, gt, depth+1
+	}
+	return nil
+}
+
+// sortKey returns the symbol at the given depth of the suffix SA[index], plus
+// one, or 0 if the suffix ends before it.
+func sortKey(text *Text, sa *SuffixArray, index, depth uint64) (uint64, error) {
+	symbol, ok, err := symbolAtDepth(text, sa, index, depth)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return symbol + 1, nil
+}
+
+func swapPositions(sa *SuffixArray, i, j uint64) error {
+	if i == j {
+		return nil
+	}
+	x, err := sa.PositionAt(i)
+	if err != nil {
+		return err
+	}
+	y, err := sa.PositionAt(j)
+	if err != nil {
+		return err
+	}
+	if err := sa.SetPositionAt(i, y); err != nil {
+		return err
+	}
+	return sa.SetPositionAt(j, x)
+}
+
+// SearchSparse performs a binary search on a sparse suffix array, as
+// constructed by BuildSparseSuffixArray.  Returns the list of selected
+// offsets which begin with the given phrase; occurrences at unselected
+// offsets are not found.
+//
+// SearchSparse passes a nil LCP-LR array to Search, because BuildLCPArray
+// needs the full suffix array to compute the heights in linear time, so the
+// search takes O(m log n) time.
+func SearchSparse(text *Text, sa *SuffixArray, phrase string) ([]uint64, error) {
+	return Search(text, sa, nil, phrase)
+}
@@ -0,0 +1,123 @@
+package suffixarray
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func isWordStart(index uint64, prev uint64, symbol uint64) bool {
+	return symbol != ' ' && symbol != '\n' && (index == 0 || prev == ' ' || prev == '\n')
+}
+
+func NaiveBuildSparseSuffixArray(text string, positions []uint64) string {
+	sorted := make([]uint64, len(positions))
+	copy(sorted, positions)
+	sort.Slice(sorted, func(i, j int) bool {
+		return text[sorted[i]:] < text[sorted[j]:]
+	})
+	return fmt.Sprintf("%v", append([]uint64{uint64(len(text))}, sorted...))
+}
+
+func TestBuildSparseSuffixArray(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		text := NewTextFromString(sampleText, opts...)
+
+		var wordStarts []uint64
+		for i := 0; i < len(sampleText); i++ {
+			prev := placeholder
+			if i > 0 {
+				prev = uint64(sampleText[i-1])
+			}
+			if isWordStart(uint64(i), prev, uint64(sampleText[i])) {
+				wordStarts = append(wordStarts, uint64(i))
+			}
+		}
+
+		sa, err := BuildSparseSuffixArray(text, PositionsWhere(text, isWordStart), opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildSparseSuffixArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		expected := NaiveBuildSparseSuffixArray(sampleText, wordStarts)
+		actual := sa.Debug()
+		if expected != actual {
+			t.Errorf("[%s] BuildSparseSuffixArray: expected %s, got %s", cfg.Name, expected, actual)
+		}
+
+		for _, phrase := range []string{searchPhrase, "dolor", "olor", "amet,", "zzz"} {
+			var expectedList []uint64
+			for _, pos := range NaiveSearch(sampleText, phrase) {
+				i := sort.Search(len(wordStarts), func(i int) bool { return wordStarts[i] >= pos })
+				if i < len(wordStarts) && wordStarts[i] == pos {
+					expectedList = append(expectedList, pos)
+				}
+			}
+
+			offsets, err := SearchSparse(text, sa, phrase)
+			if err != nil {
+				t.Errorf("[%s] SearchSparse %q: error: %v", cfg.Name, phrase, err)
+				continue
+			}
+
+			expected := fmt.Sprintf("%v", expectedList)
+			actual := fmt.Sprintf("%v", offsets)
+			if expected != actual {
+				t.Errorf("[%s] SearchSparse %q: expected %s, got %s", cfg.Name, phrase, expected, actual)
+			}
+		}
+
+		sa, err = BuildSparseSuffixArray(text, EveryKth(text, 7), opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildSparseSuffixArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		var strided []uint64
+		for i := 0; i < len(sampleText); i += 7 {
+			strided = append(strided, uint64(i))
+		}
+
+		expected = NaiveBuildSparseSuffixArray(sampleText, strided)
+		actual = sa.Debug()
+		if expected != actual {
+			t.Errorf("[%s] BuildSparseSuffixArray: expected %s, got %s", cfg.Name, expected, actual)
+		}
+	}
+}
+
+func TestBuildSparseSuffixArray_Repetitive(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		for _, input := range []string{"a", strings.Repeat("a", 300), strings.Repeat("abcab", 60), banana} {
+			for _, k := range []uint64{1, 2, 3, 8} {
+				name := fmt.Sprintf("%s/%d/%d", cfg.Name, len(input), k)
+				text := NewTextFromString(input, opts...)
+
+				sa, err := BuildSparseSuffixArray(text, EveryKth(text, k), opts...)
+				if err != nil {
+					t.Errorf("[%s] BuildSparseSuffixArray: error: %v", name, err)
+					continue
+				}
+
+				var strided []uint64
+				for i := uint64(0); i < uint64(len(input)); i += k {
+					strided = append(strided, i)
+				}
+				if expected, actual := NaiveBuildSparseSuffixArray(input, strided), sa.Debug(); expected != actual {
+					t.Errorf("[%s] BuildSparseSuffixArray: expected %s, got %s", name, expected, actual)
+				}
+				if err := sa.Close(); err != nil {
+					t.Errorf("[%s] Close: error: %v", name, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package suffixarray
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	bigarray "github.com/team-spectre/go-bigarray"
+	bigbitvector "github.com/team-spectre/go-bigbitvector"
+)
+
+// Storage is the interface through which every array in this package, from
+// Text and SuffixArray to TypeMap and the auxiliary arrays of an Index, stores
+// its values.  By default arrays are stored with go-bigarray, or with
+// go-bigbitvector for arrays of single bits; the WithStorage option plugs in
+// any other implementation.
+//
+// The public API still exchanges LCP-LR arrays as bigarray.BigArray, as it
+// did before Storage was introduced.  An array built with WithStorage is
+// returned wrapped in an adapter, which the searches unwrap again.
+//
+// A Storage holds a fixed number of values, each between 0 and MaxValue()
+// inclusive, which are all 0 when it is created.  Reading or writing past the
+// end returns io.EOF.
+//
+type Storage interface {
+	// MaxValue returns the maximum value allowed for any element.
+	MaxValue() uint64
+
+	// Len returns the number of elements.
+	Len() uint64
+
+	// ValueAt returns the value at the given index.
+	ValueAt(index uint64) (uint64, error)
+
+	// SetValueAt replaces the value at the given index.
+	SetValueAt(index uint64, value uint64) error
+
+	// Iterate returns a StorageIterator that starts at index i and stops
+	// at index j-1.
+	Iterate(i, j uint64) StorageIterator
+
+	// ReverseIterate returns a StorageIterator that starts at index j-1
+	// and stops at index i.
+	ReverseIterate(i, j uint64) StorageIterator
+
+	// Truncate trims the storage to the given length.
+	Truncate(length uint64) error
+
+	// Freeze makes the storage read-only.
+	Freeze() error
+
+	// Flush ensures that all pending writes have been stored.
+	Flush() error
+
+	// Close flushes any writes and frees the resources used by the
+	// storage.
+	Close() error
+}
+
+// StorageIterator provides sequential access to a Storage.  It follows the
+// same pattern as TextIterator: call Next() before the first item, and Close()
+// when done.
+type StorageIterator interface {
+	// Next advances to the next index and returns true, or returns false
+	// at the end of the iteration or if an error has occurred.
+	Next() bool
+
+	// Skip(n) is equivalent to calling Next() n times.
+	Skip(n uint64) bool
+
+	// Index returns the index of the current element.
+	Index() uint64
+
+	// Value returns the value of the current element.
+	Value() uint64
+
+	// SetValue replaces the value of the current element.
+	SetValue(value uint64)
+
+	// Err returns the error which caused Next() to return false.
+	Err() error
+
+	// Flush ensures that all pending writes have been stored.
+	Flush() error
+
+	// Close flushes writes and frees the resources used by the iterator.
+	Close() error
+}
+
+// StorageConfig describes the Storage which an array needs.
+type StorageConfig struct {
+	// NumValues is the number of values to hold.
+	NumValues uint64
+
+	// MaxValue is the largest value which will be stored.
+	MaxValue uint64
+}
+
+// StorageFactory constructs a Storage for the given configuration.
+type StorageFactory func(StorageConfig) (Storage, error)
+
+// makeStorage constructs a Storage from a list of options.
+func makeStorage(list []Option) (Storage, error) {
+	o := makeStorageOptions(list)
+	if o.factory != nil {
+		cfg, err := o.config()
+		if err != nil {
+			return nil, err
+		}
+		return o.factory(cfg)
+	}
+
+	out := make([]bigarray.Option, 0, len(list))
+	for _, item := range list {
+		if item.BigArrayOption != nil {
+			out = append(out, item.BigArrayOption)
+		}
+	}
+	ba, err := bigarray.New(out...)
+	if err != nil {
+		return nil, err
+	}
+	return bigArrayStorage{ba}, nil
+}
+
+// makeBitStorage constructs a Storage for values of a single bit from a list
+// of options.
+func makeBitStorage(list []Option) (Storage, error) {
+	o := makeStorageOptions(list)
+	if o.factory != nil {
+		return o.factory(StorageConfig{NumValues: o.numValues, MaxValue: 1})
+	}
+
+	out := make([]bigbitvector.Option, 0, len(list))
+	for _, item := range list {
+		if item.BigBitVectorOption != nil {
+			out = append(out, item.BigBitVectorOption)
+		}
+	}
+	bv, err := bigbitvector.New(out...)
+	if err != nil {
+		return nil, err
+	}
+	return bitVectorStorage{bv}, nil
+}
+
+// forEachValue is a convenience function that iterates over an entire Storage
+// in the forward direction.
+func forEachValue(s Storage, fn func(uint64, uint64) error) error {
+	iter := s.Iterate(0, s.Len())
+	for iter.Next() {
+		if err := fn(iter.Index(), iter.Value()); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	return iter.Close()
+}
+
+// reverseForEachValue is like forEachValue, but in the reverse direction.
+func reverseForEachValue(s Storage, fn func(uint64, uint64) error) error {
+	iter := s.ReverseIterate(0, s.Len())
+	for iter.Next() {
+		if err := fn(iter.Index(), iter.Value()); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	return iter.Close()
+}
+
+// copyStorage replaces the values of dst with those of src.  The two must
+// have the same length.
+func copyStorage(dst, src Storage) error {
+	if dst.Len() != src.Len() {
+		return fmt.Errorf("copyStorage: length mismatch: %d vs %d", dst.Len(), src.Len())
+	}
+	srcIter := src.Iterate(0, src.Len())
+	dstIter := dst.Iterate(0, dst.Len())
+	for srcIter.Next() && dstIter.Next() {
+		dstIter.SetValue(srcIter.Value())
+	}
+	err := dstIter.Close()
+	if err2 := srcIter.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// debugStorage returns a human-friendly representation of the values in a
+// Storage, with the placeholder value shown as ".".
+func debugStorage(s Storage) string {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	err := forEachValue(s, func(index uint64, value uint64) error {
+		if index > 0 {
+			buf.WriteByte(' ')
+		}
+		if value == placeholder {
+			buf.WriteByte('.')
+		} else {
+			fmt.Fprintf(&buf, "%d", value)
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	buf.WriteByte(']')
+	return buf.String()
+}
+
+// bigArrayStorage is the default Storage, backed by go-bigarray.
+type bigArrayStorage struct {
+	bigarray.BigArray
+}
+
+func (s bigArrayStorage) Iterate(i, j uint64) StorageIterator {
+	return s.BigArray.Iterate(i, j)
+}
+
+func (s bigArrayStorage) ReverseIterate(i, j uint64) StorageIterator {
+	return s.BigArray.ReverseIterate(i, j)
+}
+
+// bigArrayToStorage returns the Storage behind a bigarray.BigArray which was
+// passed to the public API, such as an LCP-LR array.  A BigArray returned by
+// storageToBigArray is unwrapped, and any other is wrapped.
+func bigArrayToStorage(ba bigarray.BigArray) Storage {
+	switch x := ba.(type) {
+	case nil:
+		return nil
+	case storageBigArray:
+		return x.Storage
+	}
+	return bigArrayStorage{ba}
+}
+
+// storageToBigArray presents a Storage as a bigarray.BigArray, for the parts
+// of the public API which exchange arrays as BigArrays.  The default Storage
+// is unwrapped, and any other is wrapped.
+func storageToBigArray(s Storage) bigarray.BigArray {
+	switch x := s.(type) {
+	case nil:
+		return nil
+	case bigArrayStorage:
+		return x.BigArray
+	}
+	return storageBigArray{s}
+}
+
+// storageBigArray adapts a Storage constructed by a StorageFactory to the
+// bigarray.BigArray interface.
+type storageBigArray struct {
+	Storage
+}
+
+func (ba storageBigArray) Frozen() bool {
+	if f, ok := ba.Storage.(interface{ Frozen() bool }); ok {
+		return f.Frozen()
+	}
+	return false
+}
+
+func (ba storageBigArray) Iterate(i, j uint64) bigarray.Iterator {
+	return ba.Storage.Iterate(i, j)
+}
+
+func (ba storageBigArray) ReverseIterate(i, j uint64) bigarray.Iterator {
+	return ba.Storage.ReverseIterate(i, j)
+}
+
+func (ba storageBigArray) CopyFrom(src bigarray.BigArray) error {
+	return copyStorage(ba.Storage, bigArrayToStorage(src))
+}
+
+func (ba storageBigArray) Debug() string { return debugStorage(ba.Storage) }
+
+// bitVectorStorage is the default Storage for single bits, backed by
+// go-bigbitvector.
+type bitVectorStorage struct {
+	bv bigbitvector.BigBitVector
+}
+
+func (s bitVectorStorage) MaxValue() uint64 { return 1 }
+
+func (s bitVectorStorage) Len() uint64 { return s.bv.Len() }
+
+func (s bitVectorStorage) ValueAt(index uint64) (uint64, error) {
+	bit, err := s.bv.BitAt(index)
+	return bitValue(bit), err
+}
+
+func (s bitVectorStorage) SetValueAt(index uint64, value uint64) error {
+	if value > 1 {
+		return fmt.Errorf("bitVectorStorage.SetValueAt: value %d is not a bit", value)
+	}
+	return s.bv.SetBitAt(index, value != 0)
+}
+
+func (s bitVectorStorage) Iterate(i, j uint64) StorageIterator {
+	return bitVectorIterator{s.bv.Iterate(i, j)}
+}
+
+func (s bitVectorStorage) ReverseIterate(i, j uint64) StorageIterator {
+	return bitVectorIterator{s.bv.ReverseIterate(i, j)}
+}
+
+func (s bitVectorStorage) Truncate(length uint64) error { return s.bv.Truncate(length) }
+
+func (s bitVectorStorage) Freeze() error { return s.bv.Freeze() }
+
+func (s bitVectorStorage) Flush() error { return s.bv.Flush() }
+
+func (s bitVectorStorage) Close() error { return s.bv.Close() }
+
+type bitVectorIterator struct {
+	bigbitvector.Iterator
+}
+
+func (iter bitVectorIterator) Value() uint64 { return bitValue(iter.Bit()) }
+
+func (iter bitVectorIterator) SetValue(value uint64) { iter.SetBit(value != 0) }
+
+func bitValue(bit bool) uint64 {
+	if bit {
+		return 1
+	}
+	return 0
+}
+
+// NewSliceStorage is a StorageFactory which keeps the values in a plain Go
+// slice of uint64, for callers who would rather trade memory for speed and
+// simplicity.  Use it as WithStorage(NewSliceStorage).
+func NewSliceStorage(cfg StorageConfig) (Storage, error) {
+	return &sliceStorage{
+		data: make([]uint64, cfg.NumValues),
+		max:  cfg.MaxValue,
+	}, nil
+}
+
+type sliceStorage struct {
+	data   []uint64
+	max    uint64
+	frozen bool
+}
+
+var errFrozenStorage = errors.New("Storage is read-only")
+
+func (s *sliceStorage) MaxValue() uint64 { return s.max }
+
+func (s *sliceStorage) Len() uint64 { return uint64(len(s.data)) }
+
+func (s *sliceStorage) ValueAt(index uint64) (uint64, error) {
+	if index >= s.Len() {
+		return placeholder, io.EOF
+	}
+	return s.data[index], nil
+}
+
+func (s *sliceStorage) SetValueAt(index uint64, value uint64) error {
+	if s.frozen {
+		return errFrozenStorage
+	}
+	if value > s.max {
+		return fmt.Errorf("sliceStorage.SetValueAt: value %d exceeds maximum %d", value, s.max)
+	}
+	if index >= s.Len() {
+		return io.EOF
+	}
+	s.data[index] = value
+	return nil
+}
+
+func (s *sliceStorage) Iterate(i, j uint64) StorageIterator {
+	if i > j || j > s.Len() {
+		panic(fmt.Errorf("sliceStorage.Iterate: invalid range [%d, %d) for length %d", i, j, s.Len()))
+	}
+	return &sliceIterator{s: s, next: i, end: j, step: 1}
+}
+
+func (s *sliceStorage) ReverseIterate(i, j uint64) StorageIterator {
+	if i > j || j > s.Len() {
+		panic(fmt.Errorf("sliceStorage.ReverseIterate: invalid range [%d, %d) for length %d", i, j, s.Len()))
+	}
+	return &sliceIterator{s: s, next: j - 1, end: i - 1, step: ^uint64(0)}
+}
+
+func (s *sliceStorage) Truncate(length uint64) error {
+	if s.frozen {
+		return errFrozenStorage
+	}
+	if length > s.Len() {
+		return fmt.Errorf("sliceStorage.Truncate: length %d exceeds current length %d", length, s.Len())
+	}
+	s.data = s.data[:length]
+	return nil
+}
+
+func (s *sliceStorage) Frozen() bool { return s.frozen }
+
+func (s *sliceStorage) Freeze() error {
+	s.frozen = true
+	return nil
+}
+
+func (s *sliceStorage) Flush() error { return nil }
+
+func (s *sliceStorage) Close() error {
+	s.data = nil
+	return nil
+}
+
+// sliceIterator walks a sliceStorage.  Indices wrap around in the reverse
+// direction, so that next == end marks the end in both directions.
+type sliceIterator struct {
+	s     *sliceStorage
+	index uint64
+	next  uint64
+	end   uint64
+	step  uint64
+	err   error
+}
+
+func (iter *sliceIterator) Next() bool { return iter.Skip(1) }
+
+func (iter *sliceIterator) Skip(n uint64) bool {
+	remaining := iter.end - iter.next
+	if iter.step != 1 {
+		remaining = iter.next - iter.end
+	}
+	if n > remaining {
+		iter.next = iter.end
+		return false
+	}
+	if n > 0 {
+		iter.index = iter.next + (n-1)*iter.step
+		iter.next = iter.index + iter.step
+	}
+	return true
+}
+
+func (iter *sliceIterator) Index() uint64 { return iter.index }
+
+func (iter *sliceIterator) Value() uint64 { return iter.s.data[iter.index] }
+
+func (iter *sliceIterator) SetValue(value uint64) {
+	if err := iter.s.SetValueAt(iter.index, value); err != nil && iter.err == nil {
+		iter.err = err
+	}
+}
+
+func (iter *sliceIterator) Err() error { return iter.err }
+
+func (iter *sliceIterator) Flush() error { return iter.err }
+
+func (iter *sliceIterator) Close() error { return iter.err }
+
+var _ Storage = bigArrayStorage{}
+var _ Storage = bitVectorStorage{}
+var _ Storage = (*sliceStorage)(nil)
+var _ bigarray.BigArray = storageBigArray{}
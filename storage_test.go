@@ -0,0 +1,129 @@
+package suffixarray
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSliceStorage(t *testing.T) {
+	s, err := NewSliceStorage(StorageConfig{NumValues: 10, MaxValue: 100})
+	if err != nil {
+		t.Fatalf("NewSliceStorage: error: %v", err)
+	}
+
+	iter := s.Iterate(0, s.Len())
+	for iter.Next() {
+		iter.SetValue(iter.Index() * 10)
+	}
+	if err := iter.Close(); err != nil {
+		t.Errorf("Close: error: %v", err)
+	}
+	if expected, actual := `[0 10 20 30 40 50 60 70 80 90]`, debugStorage(s); expected != actual {
+		t.Errorf("Iterate: expected %s, got %s", expected, actual)
+	}
+
+	var values []uint64
+	iter = s.ReverseIterate(2, 8)
+	for iter.Next() {
+		values = append(values, iter.Value())
+		iter.Skip(1)
+	}
+	if expected, actual := `[70 50 30]`, fmt.Sprintf("%v", values); expected != actual {
+		t.Errorf("ReverseIterate: expected %s, got %s", expected, actual)
+	}
+
+	if err := s.SetValueAt(3, 101); err == nil {
+		t.Errorf("SetValueAt: expected error for value above MaxValue")
+	}
+	if _, err := s.ValueAt(10); err == nil {
+		t.Errorf("ValueAt: expected error past the end")
+	}
+	if err := s.Truncate(4); err != nil || s.Len() != 4 {
+		t.Errorf("Truncate: expected length 4, got %d, %v", s.Len(), err)
+	}
+	if err := s.Freeze(); err != nil {
+		t.Errorf("Freeze: error: %v", err)
+	}
+	if err := s.SetValueAt(0, 1); err == nil {
+		t.Errorf("SetValueAt: expected error after Freeze")
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close: error: %v", err)
+	}
+}
+
+func TestWithStorage(t *testing.T) {
+	var configs []StorageConfig
+	factory := func(cfg StorageConfig) (Storage, error) {
+		configs = append(configs, cfg)
+		return NewSliceStorage(cfg)
+	}
+	opts := []Option{WithStorage(factory)}
+
+	text, err := NewText(256, extendOptions(opts, NumValues(uint64(len(sampleText))))...)
+	if err != nil {
+		t.Fatalf("NewText: error: %v", err)
+	}
+	for i := 0; i < len(sampleText); i++ {
+		if err := text.SetSymbolAt(uint64(i), uint64(sampleText[i])); err != nil {
+			t.Fatalf("SetSymbolAt: error: %v", err)
+		}
+	}
+	if len(configs) != 1 || configs[0].NumValues != uint64(len(sampleText)) || configs[0].MaxValue != 255 {
+		t.Errorf("NewText: expected one Storage of %d values up to 255, got %v", len(sampleText), configs)
+	}
+
+	idx, err := BuildIndex(text, opts...)
+	if err != nil {
+		t.Fatalf("BuildIndex: error: %v", err)
+	}
+	if err := VerifySuffixArray(idx.Text(), idx.SuffixArray(), opts...); err != nil {
+		t.Errorf("VerifySuffixArray: error: %v", err)
+	}
+
+	// Every array of the construction, including the TypeMaps, comes
+	// from the factory.
+	sawBits := false
+	for _, cfg := range configs {
+		if cfg.MaxValue == 1 {
+			sawBits = true
+		}
+	}
+	if !sawBits {
+		t.Errorf("BuildIndex: expected a Storage of bits for the TypeMap")
+	}
+
+	for _, phrase := range []string{searchPhrase, "Lorem", "a", "zzz", "sit amet"} {
+		expected := fmt.Sprintf("%v", NaiveSearch(sampleText, phrase))
+		offsets, err := SearchIndex(idx, phrase)
+		if err != nil {
+			t.Errorf("SearchIndex %q: error: %v", phrase, err)
+			continue
+		}
+		if actual := fmt.Sprintf("%v", offsets); expected != actual {
+			t.Errorf("SearchIndex %q: expected %s, got %s", phrase, expected, actual)
+		}
+
+		// The LCP-LR array is still handed out as a bigarray.BigArray.
+		offsets, err = Search(idx.Text(), idx.SuffixArray(), idx.LCPLR(), phrase)
+		if err != nil {
+			t.Errorf("Search %q: error: %v", phrase, err)
+			continue
+		}
+		if actual := fmt.Sprintf("%v", offsets); expected != actual {
+			t.Errorf("Search %q: expected %s, got %s", phrase, expected, actual)
+		}
+	}
+
+	lcplr := idx.LCPLR()
+	if _, ok := bigArrayToStorage(lcplr).(*sliceStorage); !ok {
+		t.Errorf("LCPLR: expected the factory's Storage to be unwrapped, got %T", bigArrayToStorage(lcplr))
+	}
+	if expected, actual := debugStorage(idx.lcplr), lcplr.Debug(); expected != actual {
+		t.Errorf("LCPLR: expected %s, got %s", expected, actual)
+	}
+
+	if err := idx.Close(); err != nil {
+		t.Errorf("Close: error: %v", err)
+	}
+}
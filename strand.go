@@ -0,0 +1,186 @@
+package suffixarray
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Strand identifies which strand of a DNA text a hit was found on.  A hit
+// of a palindromic pattern, one which equals its own reverse complement, is
+// found on both strands at once.
+type Strand byte
+
+const (
+	// ForwardStrand hits match the pattern itself.
+	ForwardStrand Strand = 1 << iota
+
+	// ReverseStrand hits match the reverse complement of the pattern,
+	// i.e. the pattern occurs on the opposite strand.
+	ReverseStrand
+
+	// BothStrands hits match both, which happens exactly when the
+	// pattern is palindromic.
+	BothStrands = ForwardStrand | ReverseStrand
+)
+
+// String returns "+", "-", or "+/-".
+func (s Strand) String() string {
+	switch s {
+	case ForwardStrand:
+		return "+"
+	case ReverseStrand:
+		return "-"
+	case BothStrands:
+		return "+/-"
+	}
+	return fmt.Sprintf("Strand(%d)", byte(s))
+}
+
+// StrandHit is an occurrence found by SearchBothStrands.  Offset is always
+// the forward-strand offset of the first symbol of the matching region, on
+// whichever strand the match was found.
+type StrandHit struct {
+	Offset uint64
+	Strand Strand
+}
+
+// SequenceStrandHit is an occurrence found by SearchSequenceBothStrands.
+type SequenceStrandHit struct {
+	SequenceHit
+	Strand Strand
+}
+
+// ComplementTable maps each symbol of an alphabet to its complementary
+// symbol, at the index of the symbol.
+type ComplementTable []uint64
+
+// ASCIIComplement is the ComplementTable for DNA stored as ASCII bytes, with
+// an AlphabetSize of 256.  It swaps A and T, C and G, and the IUPAC ambiguity
+// codes likewise, in either case; every other byte, including N, is its own
+// complement.
+var ASCIIComplement = func() ComplementTable {
+	table := make(ComplementTable, 256)
+	for i := range table {
+		table[i] = uint64(i)
+	}
+	for _, pair := range []string{"AT", "CG", "RY", "KM", "BV", "DH"} {
+		for _, p := range []string{pair, strings.ToLower(pair)} {
+			table[p[0]], table[p[1]] = uint64(p[1]), uint64(p[0])
+		}
+	}
+	return table
+}()
+
+// DNAComplement is the ComplementTable for the Text of a SequenceSet.
+var DNAComplement = ComplementTable{
+	DocumentSeparator: DocumentSeparator,
+	BaseA:             BaseT,
+	BaseC:             BaseG,
+	BaseG:             BaseC,
+	BaseN:             BaseN,
+	BaseT:             BaseA,
+}
+
+// ReverseComplement returns the reverse complement of a pattern: the pattern
+// read backward, with each symbol replaced by its complement.
+func (table ComplementTable) ReverseComplement(pattern []uint64) ([]uint64, error) {
+	out := make([]uint64, len(pattern))
+	for i, symbol := range pattern {
+		if symbol >= uint64(len(table)) {
+			return nil, fmt.Errorf("ComplementTable: symbol %d is outside the table of %d symbols", symbol, len(table))
+		}
+		out[len(pattern)-1-i] = table[symbol]
+	}
+	return out, nil
+}
+
+// SearchBothStrands searches an Index of a DNA text for a pattern and for its
+// reverse complement, using the given ComplementTable, and returns the hits
+// on either strand in ascending order of offset.  If the pattern is
+// palindromic, each site is reported once, as BothStrands.
+func SearchBothStrands(idx *Index, pattern string, table ComplementTable) ([]StrandHit, error) {
+	return SearchBothStrandsSymbols(idx, stringToSymbols(pattern), table)
+}
+
+// SearchBothStrandsSymbols is like SearchBothStrands, but the pattern is
+// given as a list of symbols.
+func SearchBothStrandsSymbols(idx *Index, pattern []uint64, table ComplementTable) ([]StrandHit, error) {
+	rc, err := table.ReverseComplement(pattern)
+	if err != nil {
+		return nil, err
+	}
+	pattern, rc = idx.prepare(pattern), idx.prepare(rc)
+
+	forward, err := searchSymbols(idx.searchText(), idx.sa, idx.lcplr, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if equalSymbols(pattern, rc) {
+		return mergeStrands(forward, forward), nil
+	}
+
+	reverse, err := searchSymbols(idx.searchText(), idx.sa, idx.lcplr, rc)
+	if err != nil {
+		return nil, err
+	}
+	return mergeStrands(forward, reverse), nil
+}
+
+// SearchSequenceBothStrands is like SearchSequence, but also reports the
+// occurrences of the reverse complement of seq, as by SearchBothStrands.
+func SearchSequenceBothStrands(si *SequenceIndex, seq string) ([]SequenceStrandHit, error) {
+	symbols, ok := si.seqs.Symbols(seq)
+	if !ok {
+		return nil, nil
+	}
+
+	found, err := SearchBothStrandsSymbols(si.di.idx, symbols, DNAComplement)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]SequenceStrandHit, len(found))
+	for i, hit := range found {
+		hits[i].SequenceHit, err = si.locate(hit.Offset)
+		if err != nil {
+			return nil, err
+		}
+		hits[i].Strand = hit.Strand
+	}
+	return hits, nil
+}
+
+// mergeStrands merges the sorted offsets of the forward and reverse hits.
+// An offset in both lists, which only happens for a palindromic pattern, is
+// reported once for both strands.
+func mergeStrands(forward, reverse []uint64) []StrandHit {
+	hits := make([]StrandHit, 0, len(forward)+len(reverse))
+	i, j := 0, 0
+	for i < len(forward) || j < len(reverse) {
+		switch {
+		case j == len(reverse) || (i < len(forward) && forward[i] < reverse[j]):
+			hits = append(hits, StrandHit{forward[i], ForwardStrand})
+			i++
+		case i == len(forward) || reverse[j] < forward[i]:
+			hits = append(hits, StrandHit{reverse[j], ReverseStrand})
+			j++
+		default:
+			hits = append(hits, StrandHit{forward[i], BothStrands})
+			i++
+			j++
+		}
+	}
+	return hits
+}
+
+func equalSymbols(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
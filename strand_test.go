@@ -0,0 +1,100 @@
+package suffixarray
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func NaiveSearchBothStrands(text, pattern string) []StrandHit {
+	rc := make([]byte, len(pattern))
+	for i := 0; i < len(pattern); i++ {
+		rc[len(pattern)-1-i] = byte(ASCIIComplement[pattern[i]])
+	}
+
+	var hits []StrandHit
+	for i := 0; i+len(pattern) <= len(text); i++ {
+		var strand Strand
+		if text[i:i+len(pattern)] == pattern {
+			strand |= ForwardStrand
+		}
+		if text[i:i+len(pattern)] == string(rc) {
+			strand |= ReverseStrand
+		}
+		if strand != 0 {
+			hits = append(hits, StrandHit{uint64(i), strand})
+		}
+	}
+	return hits
+}
+
+func TestSearchBothStrands(t *testing.T) {
+	var buf strings.Builder
+	for i := 0; i < 3000; i++ {
+		buf.WriteByte("ACGT"[(i*i+i/5)%4])
+	}
+	buf.WriteString("GAATTCnnGATTACAxTGTAATC")
+	dna := buf.String()
+
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		idx, err := BuildIndex(NewTextFromString(dna, opts...), opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildIndex: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for _, pattern := range []string{"GATTACA", "GAATTC", "ACGT", "AAC", "GG", "CAGTTA", "nn"} {
+			expected := fmt.Sprintf("%v", NaiveSearchBothStrands(dna, pattern))
+			hits, err := SearchBothStrands(idx, pattern, ASCIIComplement)
+			if err != nil {
+				t.Errorf("[%s] SearchBothStrands %q: error: %v", cfg.Name, pattern, err)
+				continue
+			}
+			if actual := fmt.Sprintf("%v", hits); expected != actual {
+				t.Errorf("[%s] SearchBothStrands %q: expected %s, got %s", cfg.Name, pattern, expected, actual)
+			}
+		}
+
+		if _, err := SearchBothStrands(idx, "ACGT", DNAComplement); err == nil {
+			t.Errorf("[%s] SearchBothStrands: expected error for symbols outside the table", cfg.Name)
+		}
+
+		if err := idx.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
+
+func TestSearchSequenceBothStrands(t *testing.T) {
+	seqs, err := ReadFASTA(strings.NewReader(sampleFASTA))
+	if err != nil {
+		t.Fatalf("ReadFASTA: error: %v", err)
+	}
+	si, err := BuildSequenceIndex(seqs)
+	if err != nil {
+		seqs.Close()
+		t.Fatalf("BuildSequenceIndex: error: %v", err)
+	}
+
+	for _, q := range [][2]string{
+		{"TGTAATC", "[{{0 12} -} {{1 0} -} {{3 4} -}]"},
+		{"ACGT", "[{{0 0} +/-} {{0 4} +/-} {{1 9} +/-}]"},
+		{"TTTTG", "[{{3 0} +}]"},
+		{"CAAAA", "[{{3 0} -}]"},
+		{"GGG", "[]"},
+	} {
+		hits, err := SearchSequenceBothStrands(si, q[0])
+		if err != nil {
+			t.Errorf("SearchSequenceBothStrands %q: error: %v", q[0], err)
+		} else if actual := fmt.Sprintf("%v", hits); actual != q[1] {
+			t.Errorf("SearchSequenceBothStrands %q: expected %s, got %s", q[0], q[1], actual)
+		}
+	}
+
+	if err := si.Close(); err != nil {
+		t.Errorf("Close: error: %v", err)
+	}
+}
@@ -1,9 +1,5 @@
 package suffixarray
 
-import (
-	bigarray "github.com/team-spectre/go-bigarray"
-)
-
 // SuffixArray represents a suffix array.
 //
 // A suffix array is a sequence of offsets into a text, with each offset N
@@ -12,17 +8,17 @@ import (
 // the end of the text.
 //
 type SuffixArray struct {
-	ba bigarray.BigArray
+	ba Storage
 }
 
 // Iterator iterates through a SuffixArray.
 type Iterator struct {
-	impl bigarray.Iterator
+	impl StorageIterator
 }
 
 // New constructs a new SuffixArray.
 func New(opts ...Option) (*SuffixArray, error) {
-	ba, err := makeBigArray(opts)
+	ba, err := makeStorage(opts)
 	if err != nil {
 		return nil, err
 	}
@@ -61,19 +57,19 @@ func (sa *SuffixArray) ReverseIterate(i, j uint64) *Iterator {
 
 // ForEach is a convenience method that forward-iterates over the entire array.
 func (sa *SuffixArray) ForEach(fn func(uint64, uint64) error) error {
-	return bigarray.ForEach(sa.ba, fn)
+	return forEachValue(sa.ba, fn)
 }
 
 // ReverseForEach is a convenience method that reverse-iterates over the entire array.
 func (sa *SuffixArray) ReverseForEach(fn func(uint64, uint64) error) error {
-	return bigarray.ReverseForEach(sa.ba, fn)
+	return reverseForEachValue(sa.ba, fn)
 }
 
 // CopyFrom copies the text offsets from another SuffixArray to this one.  The
 // two arrays must have the same Len(), and this text must have a MaxValue()
 // large enough to accommodate any offset in the source array.
 func (sa *SuffixArray) CopyFrom(src *SuffixArray) error {
-	return sa.ba.CopyFrom(src.ba)
+	return copyStorage(sa.ba, src.ba)
 }
 
 // Truncate trims the array to the given length.
@@ -89,7 +85,7 @@ func (sa *SuffixArray) Flush() error { return sa.ba.Flush() }
 func (sa *SuffixArray) Close() error { return sa.ba.Close() }
 
 // Debug returns a human-friendly debugging representation of the array.
-func (sa *SuffixArray) Debug() string { return sa.ba.Debug() }
+func (sa *SuffixArray) Debug() string { return debugStorage(sa.ba) }
 
 // Clear overwrites the array with the placeholder value 2^64-1.
 func (sa *SuffixArray) Clear() error {
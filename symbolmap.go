@@ -0,0 +1,147 @@
+package suffixarray
+
+import (
+	"sort"
+)
+
+// denseSymbolLimit is the largest alphabet for which Compact tracks symbol
+// usage with a flat table instead of a map.
+const denseSymbolLimit = 1 << 16
+
+// SymbolMap translates between the symbols of a Text and the dense symbols of
+// its compacted form, as produced by Text.Compact.
+//
+// Dense symbols are assigned in increasing order of the original symbols, so
+// the mapping preserves lexicographic order: a suffix array built from the
+// compacted Text is identical to one built from the original.
+//
+type SymbolMap struct {
+	symbols []uint64
+}
+
+func newSymbolMap(symbols []uint64) *SymbolMap {
+	sort.Sort(byU64(symbols))
+	return &SymbolMap{symbols: symbols}
+}
+
+// Len returns the number of distinct symbols, which is also the AlphabetSize
+// of the compacted Text.
+func (m *SymbolMap) Len() uint64 { return uint64(len(m.symbols)) }
+
+// Symbol returns the original symbol for the given dense symbol.
+func (m *SymbolMap) Symbol(dense uint64) uint64 { return m.symbols[dense] }
+
+// Lookup returns the dense symbol for the given original symbol.  Returns
+// false if the symbol does not occur in the text.
+func (m *SymbolMap) Lookup(symbol uint64) (uint64, bool) {
+	index := sort.Search(len(m.symbols), func(i int) bool { return m.symbols[i] >= symbol })
+	if index >= len(m.symbols) || m.symbols[index] != symbol {
+		return 0, false
+	}
+	return uint64(index), true
+}
+
+// Translate converts a phrase of original symbols into dense symbols,
+// suitable for SearchSymbols against the compacted Text.  Returns false if
+// the phrase contains a symbol which does not occur in the text, in which
+// case the phrase cannot match anywhere.
+func (m *SymbolMap) Translate(phrase []uint64) ([]uint64, bool) {
+	out := make([]uint64, len(phrase))
+	for i, symbol := range phrase {
+		dense, ok := m.Lookup(symbol)
+		if !ok {
+			return nil, false
+		}
+		out[i] = dense
+	}
+	return out, true
+}
+
+// TranslateString is like Translate, but for byte strings.
+func (m *SymbolMap) TranslateString(phrase string) ([]uint64, bool) {
+	return m.Translate(stringToSymbols(phrase))
+}
+
+// Compact scans the Text, assigns dense symbols to the symbols which actually
+// occur in it, and returns a remapped copy of the Text with the smallest
+// possible AlphabetSize, together with the SymbolMap relating the two.
+//
+// The suffix array construction allocates per-symbol buckets for every symbol
+// in the alphabet, so compacting a Text over a large but sparsely used
+// alphabet can save a great deal of time and memory.  Offsets are unchanged by
+// compaction, so search results against the compacted Text apply directly to
+// the original.
+//
+func (text *Text) Compact(opts ...Option) (*Text, *SymbolMap, error) {
+	var symbols []uint64
+	var dense []uint64
+	if text.AlphabetSize() <= denseSymbolLimit {
+		seen := make([]bool, text.AlphabetSize())
+		err := text.ForEach(func(_ uint64, symbol uint64) error {
+			seen[symbol] = true
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		dense = make([]uint64, text.AlphabetSize())
+		for symbol, used := range seen {
+			if used {
+				dense[symbol] = uint64(len(symbols))
+				symbols = append(symbols, uint64(symbol))
+			}
+		}
+	} else {
+		seen := make(map[uint64]struct{})
+		err := text.ForEach(func(_ uint64, symbol uint64) error {
+			seen[symbol] = struct{}{}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		symbols = make([]uint64, 0, len(seen))
+		for symbol := range seen {
+			symbols = append(symbols, symbol)
+		}
+	}
+	m := newSymbolMap(symbols)
+
+	alphaSize := m.Len()
+	if alphaSize == 0 {
+		alphaSize = 1
+	}
+
+	opts = extendOptions(
+		opts,
+		NumValues(text.Len()))
+
+	compact, err := NewText(alphaSize, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	srcIter := text.Iterate(0, text.Len())
+	dstIter := compact.Iterate(0, compact.Len())
+	for srcIter.Next() && dstIter.Next() {
+		symbol := srcIter.Symbol()
+		if dense != nil {
+			dstIter.SetSymbol(dense[symbol])
+		} else {
+			value, _ := m.Lookup(symbol)
+			dstIter.SetSymbol(value)
+		}
+	}
+	err = srcIter.Close()
+	if err2 := dstIter.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		compact.Close()
+		return nil, nil, err
+	}
+
+	return compact, m, nil
+}
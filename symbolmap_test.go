@@ -0,0 +1,112 @@
+package suffixarray
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestText_Compact(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		text := NewTextFromString(sampleText, opts...)
+
+		compact, m, err := text.Compact(opts...)
+		if err != nil {
+			t.Errorf("[%s] Compact: error: %v", cfg.Name, err)
+			continue
+		}
+
+		distinct := make(map[byte]struct{})
+		for i := 0; i < len(sampleText); i++ {
+			distinct[sampleText[i]] = struct{}{}
+		}
+		if compact.AlphabetSize() != uint64(len(distinct)) || m.Len() != uint64(len(distinct)) {
+			t.Errorf("[%s] Compact: expected alphabet of %d, got %d (map %d)", cfg.Name, len(distinct), compact.AlphabetSize(), m.Len())
+		}
+
+		sa0, err := BuildSuffixArray(text, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildSuffixArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		sa1, err := BuildSuffixArray(compact, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildSuffixArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		if expected, actual := sa0.Debug(), sa1.Debug(); expected != actual {
+			t.Errorf("[%s] BuildSuffixArray: compacted text sorts differently:\n%s\n%s", cfg.Name, expected, actual)
+		}
+
+		lcp, err := BuildLCPArray(compact, sa1, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildLCPArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		lcplr, err := BuildLCPLRArray(lcp, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildLCPLRArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for _, phrase := range []string{searchPhrase, "Lorem", "\n\n", "~"} {
+			var offsets []uint64
+			symbols, ok := m.TranslateString(phrase)
+			if ok {
+				offsets, err = SearchSymbols(compact, sa1, lcplr, symbols)
+				if err != nil {
+					t.Errorf("[%s] SearchSymbols %q: error: %v", cfg.Name, phrase, err)
+					continue
+				}
+			}
+
+			expected := fmt.Sprintf("%v", NaiveSearch(sampleText, phrase))
+			actual := fmt.Sprintf("%v", offsets)
+			if expected != actual {
+				t.Errorf("[%s] SearchSymbols %q: expected %s, got %s", cfg.Name, phrase, expected, actual)
+			}
+		}
+	}
+}
+
+func TestText_CompactSparseAlphabet(t *testing.T) {
+	input := []uint64{4000000000, 7, 123456789, 7, 4000000000, 7, 123456789}
+
+	text, err := NewText(1<<32, NumValues(uint64(len(input))))
+	if err != nil {
+		t.Fatalf("NewText: error: %v", err)
+	}
+	for i, symbol := range input {
+		if err := text.SetSymbolAt(uint64(i), symbol); err != nil {
+			t.Fatalf("SetSymbolAt: error: %v", err)
+		}
+	}
+
+	compact, m, err := text.Compact()
+	if err != nil {
+		t.Fatalf("Compact: error: %v", err)
+	}
+
+	if expected, actual := `[2 0 1 0 2 0 1]`, compact.Debug(); expected != actual {
+		t.Errorf("Compact: expected %s, got %s", expected, actual)
+	}
+	if expected, actual := uint64(123456789), m.Symbol(1); expected != actual {
+		t.Errorf("Symbol: expected %d, got %d", expected, actual)
+	}
+	if _, ok := m.Lookup(8); ok {
+		t.Errorf("Lookup: expected symbol 8 to be absent")
+	}
+
+	sa, err := BuildSuffixArray(compact)
+	if err != nil {
+		t.Fatalf("BuildSuffixArray: error: %v", err)
+	}
+	if expected, actual := `[7 5 1 3 6 2 4 0]`, sa.Debug(); expected != actual {
+		t.Errorf("BuildSuffixArray: expected %s, got %s", expected, actual)
+	}
+}
@@ -3,15 +3,13 @@ package suffixarray
 import (
 	"bytes"
 	"fmt"
-
-	bigarray "github.com/team-spectre/go-bigarray"
 )
 
 // Text provides an interface for dealing with very large strings that draw
 // symbols from a variable-sized alphabet.
 type Text struct {
 	ab uint64
-	ba bigarray.BigArray
+	ba Storage
 }
 
 // TextIterator iterates over a Text.
@@ -31,10 +29,11 @@ type Text struct {
 // Next() to advance to the first item.
 //
 type TextIterator struct {
-	impl bigarray.Iterator
+	impl StorageIterator
 }
 
-// NewText constructs a Text with the given alphabet.
+// NewText constructs a Text with the given alphabet.  With the PackedSymbols
+// option, the symbols are bit-packed.
 func NewText(alphaSize uint64, opts ...Option) (*Text, error) {
 	maxValue := alphaSize
 	if maxValue > 1 {
@@ -45,13 +44,22 @@ func NewText(alphaSize uint64, opts ...Option) (*Text, error) {
 		opts,
 		MaxValue(maxValue))
 
-	ba, err := makeBigArray(opts)
+	ba, err := makeTextStorage(opts)
 	if err != nil {
 		return nil, err
 	}
 	return &Text{ab: alphaSize, ba: ba}, nil
 }
 
+// makeTextStorage constructs the Storage for the symbols of a Text, which is
+// bit-packed if the PackedSymbols option is given.
+func makeTextStorage(list []Option) (Storage, error) {
+	if makeStorageOptions(list).packed {
+		return makePackedStorage(list)
+	}
+	return makeStorage(list)
+}
+
 // AlphabetSize returns the number of symbols in this text's alphabet.
 func (text *Text) AlphabetSize() uint64 { return text.ab }
 
@@ -81,20 +89,20 @@ func (text *Text) ReverseIterate(i, j uint64) *TextIterator {
 // ForEach is a convenience method that iterates over the entire TextMap in the
 // forward direction.
 func (text *Text) ForEach(fn func(uint64, uint64) error) error {
-	return bigarray.ForEach(text.ba, fn)
+	return forEachValue(text.ba, fn)
 }
 
 // ReverseForEach is a convenience method that iterates over the entire TextMap
 // in the reverse direction.
 func (text *Text) ReverseForEach(fn func(uint64, uint64) error) error {
-	return bigarray.ReverseForEach(text.ba, fn)
+	return reverseForEachValue(text.ba, fn)
 }
 
 // CopyFrom copies the symbols from another Text to this one.  The two Texts
 // must have the same Len(), and this text must have an AlphabetSize large
 // enough to accommodate any symbol in the source text.
 func (text *Text) CopyFrom(src *Text) error {
-	return text.ba.CopyFrom(src.ba)
+	return copyStorage(text.ba, src.ba)
 }
 
 // Truncate trims the Text to the given length.
@@ -0,0 +1,320 @@
+package suffixarray
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	bigarray "github.com/team-spectre/go-bigarray"
+)
+
+// Token is a single token produced by a Tokenizer.
+type Token struct {
+	// Text is the token's text, which is used as its key in the token
+	// dictionary.  Tokenizers may normalize it, e.g. by case folding.
+	Text string
+
+	// Offset is the byte offset of the token in the original input.
+	Offset uint64
+}
+
+// Tokenizer splits an input stream into tokens.
+type Tokenizer interface {
+	// Tokenize reads r until EOF, calling fn for each token in order of
+	// increasing Offset.  If fn returns an error, Tokenize must stop and
+	// return that error.
+	Tokenize(r io.Reader, fn func(Token) error) error
+}
+
+// TokenizerFunc adapts an ordinary function to the Tokenizer interface.
+type TokenizerFunc func(r io.Reader, fn func(Token) error) error
+
+// Tokenize calls f(r, fn).
+func (f TokenizerFunc) Tokenize(r io.Reader, fn func(Token) error) error { return f(r, fn) }
+
+// WordTokenizer is a Tokenizer which produces each maximal run of Unicode
+// letters and digits as a token, discarding everything else.
+var WordTokenizer Tokenizer = TokenizerFunc(tokenizeWords)
+
+func tokenizeWords(r io.Reader, fn func(Token) error) error {
+	var word strings.Builder
+	var wordOffset uint64
+	br := bufio.NewReader(r)
+	offset := uint64(0)
+	for {
+		ch, size, err := br.ReadRune()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err == nil && (unicode.IsLetter(ch) || unicode.IsDigit(ch)) {
+			if word.Len() == 0 {
+				wordOffset = offset
+			}
+			word.WriteRune(ch)
+		} else if word.Len() > 0 {
+			if err := fn(Token{Text: word.String(), Offset: wordOffset}); err != nil {
+				return err
+			}
+			word.Reset()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		offset += uint64(size)
+	}
+}
+
+// TokenDictionary maps between token strings and the symbols used to
+// represent them in a TokenIndex.  Tokens are numbered in lexicographic order.
+type TokenDictionary struct {
+	tokens []string
+}
+
+// Len returns the number of distinct tokens.
+func (dict *TokenDictionary) Len() uint64 { return uint64(len(dict.tokens)) }
+
+// Token returns the token string for the given symbol.
+func (dict *TokenDictionary) Token(symbol uint64) string { return dict.tokens[symbol] }
+
+// Lookup returns the symbol for the given token string.  Returns false if the
+// token never appears in the input.
+func (dict *TokenDictionary) Lookup(token string) (uint64, bool) {
+	index := sort.SearchStrings(dict.tokens, token)
+	if index >= len(dict.tokens) || dict.tokens[index] != token {
+		return 0, false
+	}
+	return uint64(index), true
+}
+
+// TokenIndex is a word-level index: its Text has one symbol per token, so
+// suffixes begin only at token boundaries and phrases match whole tokens.
+type TokenIndex struct {
+	tokenizer Tokenizer
+	dict      *TokenDictionary
+	text      *Text
+	sa        *SuffixArray
+	lcplr     Storage
+	offsets   Storage
+}
+
+// BuildTokenIndex tokenizes r, builds a dictionary of the distinct tokens and
+// a Text of token symbols, and constructs the suffix array and LCP-LR array
+// for that Text.
+func BuildTokenIndex(r io.Reader, tokenizer Tokenizer, opts ...Option) (*TokenIndex, error) {
+	tempOpts := extendOptions(
+		opts,
+		BytesPerValue(8),
+		WithFile(nil))
+
+	provisional, err := newArrayBuilder(tempOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer provisional.Close()
+
+	offsets, err := newArrayBuilder(tempOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer offsets.Close()
+
+	// Symbols are first assigned in order of appearance, then renumbered
+	// once the full dictionary is known and can be sorted.
+	seen := make(map[string]uint64)
+	var tokens []string
+	err = tokenizer.Tokenize(r, func(token Token) error {
+		symbol, found := seen[token.Text]
+		if !found {
+			symbol = uint64(len(tokens))
+			seen[token.Text] = symbol
+			tokens = append(tokens, token.Text)
+		}
+		if err := provisional.Append(symbol); err != nil {
+			return err
+		}
+		return offsets.Append(token.Offset)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	renumber := make([]uint64, len(tokens))
+	sorted := make([]string, len(tokens))
+	copy(sorted, tokens)
+	sort.Strings(sorted)
+	for index, token := range sorted {
+		renumber[seen[token]] = uint64(index)
+	}
+
+	alphaSize := uint64(len(sorted))
+	if alphaSize == 0 {
+		alphaSize = 1
+	}
+
+	textOpts := extendOptions(
+		opts,
+		NumValues(provisional.Len()))
+
+	text, err := NewText(alphaSize, textOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &TokenIndex{
+		tokenizer: tokenizer,
+		dict:      &TokenDictionary{tokens: sorted},
+		text:      text,
+	}
+
+	needClose := true
+	defer func() {
+		if needClose {
+			idx.Close()
+		}
+	}()
+
+	raw, err := provisional.Finish()
+	if err != nil {
+		return nil, err
+	}
+	defer raw.Close()
+
+	rawIter := raw.Iterate(0, raw.Len())
+	textIter := text.Iterate(0, text.Len())
+	for rawIter.Next() && textIter.Next() {
+		textIter.SetSymbol(renumber[rawIter.Value()])
+	}
+	if err := rawIter.Close(); err != nil {
+		textIter.Close()
+		return nil, err
+	}
+	if err := textIter.Close(); err != nil {
+		return nil, err
+	}
+
+	idx.offsets, err = offsets.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	idx.sa, err = BuildSuffixArray(text, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	lcp, err := BuildLCPArray(text, idx.sa, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer lcp.Close()
+
+	idx.lcplr, err = buildLCPLRArray(lcp, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	needClose = false
+	return idx, nil
+}
+
+// Dictionary returns the token dictionary.
+func (idx *TokenIndex) Dictionary() *TokenDictionary { return idx.dict }
+
+// Text returns the Text of token symbols.
+func (idx *TokenIndex) Text() *Text { return idx.text }
+
+// SuffixArray returns the suffix array of the token Text.
+func (idx *TokenIndex) SuffixArray() *SuffixArray { return idx.sa }
+
+// LCPLR returns the LCP-LR array of the token Text.
+func (idx *TokenIndex) LCPLR() bigarray.BigArray { return storageToBigArray(idx.lcplr) }
+
+// Len returns the number of tokens in the index.
+func (idx *TokenIndex) Len() uint64 { return idx.text.Len() }
+
+// ByteOffset returns the byte offset in the original input of the token at
+// the given token offset.
+func (idx *TokenIndex) ByteOffset(index uint64) (uint64, error) {
+	return idx.offsets.ValueAt(index)
+}
+
+// Symbols tokenizes a phrase with the index's Tokenizer and converts it into
+// a list of token symbols.  Returns false if the phrase contains a token which
+// never appears in the index.
+func (idx *TokenIndex) Symbols(phrase string) ([]uint64, bool, error) {
+	var out []uint64
+	ok := true
+	err := idx.tokenizer.Tokenize(strings.NewReader(phrase), func(token Token) error {
+		symbol, found := idx.dict.Lookup(token.Text)
+		if !found {
+			ok = false
+		}
+		out = append(out, symbol)
+		return nil
+	})
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+// Close frees the resources used by the TokenIndex.
+func (idx *TokenIndex) Close() error {
+	var closers []io.Closer
+	if idx.text != nil {
+		closers = append(closers, idx.text)
+	}
+	if idx.sa != nil {
+		closers = append(closers, idx.sa)
+	}
+	if idx.lcplr != nil {
+		closers = append(closers, idx.lcplr)
+	}
+	if idx.offsets != nil {
+		closers = append(closers, idx.offsets)
+	}
+
+	var finalError error
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	return finalError
+}
+
+// SearchTokens tokenizes the phrase and returns the byte offsets in the
+// original input at which that sequence of tokens begins.  A phrase with no
+// tokens matches nothing.
+func SearchTokens(idx *TokenIndex, phrase string) ([]uint64, error) {
+	symbols, ok, err := idx.Symbols(phrase)
+	if err != nil || !ok || len(symbols) == 0 {
+		return nil, err
+	}
+
+	results, err := searchSymbols(idx.text, idx.sa, idx.lcplr, symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, pos := range results {
+		results[i], err = idx.ByteOffset(pos)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// CountTokens returns the number of occurrences of the phrase's tokens.
+func CountTokens(idx *TokenIndex, phrase string) (uint64, error) {
+	symbols, ok, err := idx.Symbols(phrase)
+	if err != nil || !ok || len(symbols) == 0 {
+		return 0, err
+	}
+
+	lo, hi, err := rangeSymbols(idx.text, idx.sa, idx.lcplr, symbols)
+	return hi - lo, err
+}
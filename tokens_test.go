@@ -0,0 +1,92 @@
+package suffixarray
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func NaiveSearchTokens(text, phrase string) []uint64 {
+	re := regexp.MustCompile(`[\pL\pN]+`)
+	textTokens := re.FindAllStringIndex(text, -1)
+	phraseTokens := re.FindAllString(phrase, -1)
+	if len(phraseTokens) == 0 {
+		return nil
+	}
+
+	var out []uint64
+	for i := 0; i+len(phraseTokens) <= len(textTokens); i++ {
+		match := true
+		for j, token := range phraseTokens {
+			loc := textTokens[i+j]
+			if text[loc[0]:loc[1]] != token {
+				match = false
+				break
+			}
+		}
+		if match {
+			out = append(out, uint64(textTokens[i][0]))
+		}
+	}
+	return out
+}
+
+func TestTokenIndex_Search(t *testing.T) {
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		idx, err := BuildTokenIndex(strings.NewReader(sampleText), WordTokenizer, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildTokenIndex: error: %v", cfg.Name, err)
+			continue
+		}
+
+		if expected, actual := "Aenean", idx.Dictionary().Token(0); expected != actual {
+			t.Errorf("[%s] Dictionary: expected first token %q, got %q", cfg.Name, expected, actual)
+		}
+
+		for i, phrase := range []string{
+			"sit amet",
+			"sit, amet!",
+			"odio",
+			"od",
+			"Phasellus nec",
+			"dolor in nunc",
+			"lorem ipsum",
+			"...",
+			"nonexistent words",
+			"sit amet nonexistent",
+			"Phasellus nex",
+			"dolor sit amex",
+			"odio odio odio",
+		} {
+			offsets, err := SearchTokens(idx, phrase)
+			if err != nil {
+				t.Errorf("[%s/%03d] SearchTokens %q: error: %v", cfg.Name, i, phrase, err)
+				continue
+			}
+
+			expectedList := NaiveSearchTokens(sampleText, phrase)
+			expected := fmt.Sprintf("%v", expectedList)
+			actual := fmt.Sprintf("%v", offsets)
+			if expected != actual {
+				t.Errorf("[%s/%03d] SearchTokens %q: expected %s, got %s", cfg.Name, i, phrase, expected, actual)
+			}
+
+			count, err := CountTokens(idx, phrase)
+			if err != nil {
+				t.Errorf("[%s/%03d] CountTokens %q: error: %v", cfg.Name, i, phrase, err)
+				continue
+			}
+			if count != uint64(len(expectedList)) {
+				t.Errorf("[%s/%03d] CountTokens %q: expected %d, got %d", cfg.Name, i, phrase, len(expectedList), count)
+			}
+		}
+
+		if err := idx.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
@@ -2,8 +2,6 @@ package suffixarray
 
 import (
 	"bytes"
-
-	bigbitvector "github.com/team-spectre/go-bigbitvector"
 )
 
 const (
@@ -18,7 +16,7 @@ const (
 
 // TypeMap catalogues the SA-IS type of each symbol in a text.
 type TypeMap struct {
-	bv bigbitvector.BigBitVector
+	bv Storage
 }
 
 // TypeMapIterator iterates over a TypeMap.
@@ -38,14 +36,14 @@ type TypeMap struct {
 // Next() to advance to the first item.
 //
 type TypeMapIterator struct {
-	impl   bigbitvector.Iterator
+	impl   StorageIterator
 	last   bool
 	primed bool
 }
 
 // NewTypeMap constructs a new TypeMap.
 func NewTypeMap(opts ...Option) (*TypeMap, error) {
-	bv, err := makeBigBitVector(opts)
+	bv, err := makeBitStorage(opts)
 	if err != nil {
 		return nil, err
 	}
@@ -57,12 +55,13 @@ func (typeMap *TypeMap) Len() uint64 { return typeMap.bv.Len() }
 
 // TypeAt returns the type for the symbol at index.
 func (typeMap *TypeMap) TypeAt(index uint64) (bool, error) {
-	return typeMap.bv.BitAt(index)
+	value, err := typeMap.bv.ValueAt(index)
+	return value != 0, err
 }
 
 // SetTypeAt replaces the type for the symbol at index.
 func (typeMap *TypeMap) SetTypeAt(index uint64, typeBit bool) error {
-	return typeMap.bv.SetBitAt(index, typeBit)
+	return typeMap.bv.SetValueAt(index, bitValue(typeBit))
 }
 
 // Iterate constructs a TypeMapIterator in the forward direction.
@@ -86,7 +85,7 @@ func (typeMap *TypeMap) ForEach(fn func(uint64, bool, bool) error) error {
 // CopyFrom copies the symbol types from another TypeMap to this one.  The two
 // TypeMaps must have the same Len().
 func (typeMap *TypeMap) CopyFrom(src *TypeMap) error {
-	return typeMap.bv.CopyFrom(src.bv)
+	return copyStorage(typeMap.bv, src.bv)
 }
 
 // Truncate trims the TypeMap to the given length.
@@ -106,11 +105,11 @@ func (typeMap *TypeMap) IsLMS(index uint64) (bool, error) {
 	if index < 1 {
 		return false, nil
 	}
-	a, err := typeMap.bv.BitAt(index - 1)
+	a, err := typeMap.TypeAt(index - 1)
 	if err != nil {
 		return false, err
 	}
-	b, err := typeMap.bv.BitAt(index)
+	b, err := typeMap.TypeAt(index)
 	if err != nil {
 		return false, err
 	}
@@ -163,7 +162,8 @@ func (typeMap *TypeMap) Debug() string {
 	var buf bytes.Buffer
 	var last bool
 	buf.WriteByte('[')
-	err := bigbitvector.ForEach(typeMap.bv, func(index uint64, bit bool) error {
+	err := forEachValue(typeMap.bv, func(index uint64, value uint64) error {
+		bit := value != 0
 		if index > 0 && last == LType && bit == SType {
 			buf.WriteByte('@')
 		} else if bit == SType {
@@ -190,13 +190,13 @@ func (iter *TypeMapIterator) Next() bool { return iter.Skip(1) }
 func (iter *TypeMapIterator) Index() uint64 { return iter.impl.Index() }
 
 // Type returns the type of the current symbol.
-func (iter *TypeMapIterator) Type() bool { return iter.impl.Bit() }
+func (iter *TypeMapIterator) Type() bool { return iter.impl.Value() != 0 }
 
 // IsLMS returns true iff the current symbol is a "left-most S".
 func (iter *TypeMapIterator) IsLMS() bool { return iter.last == LType && iter.Type() == SType }
 
 // SetType replaces the type for the current symbol.
-func (iter *TypeMapIterator) SetType(bit bool) { iter.impl.SetBit(bit) }
+func (iter *TypeMapIterator) SetType(bit bool) { iter.impl.SetValue(bitValue(bit)) }
 
 // Err returns the error which caused Next() to return false.
 func (iter *TypeMapIterator) Err() error { return iter.impl.Err() }
@@ -210,7 +210,7 @@ func (iter *TypeMapIterator) Close() error { return iter.impl.Close() }
 // Skip is equivalent to calling Next() n times, but faster.
 func (iter *TypeMapIterator) Skip(n uint64) bool {
 	if n == 1 && iter.primed {
-		iter.last = iter.impl.Bit()
+		iter.last = iter.Type()
 	} else if n == 1 {
 		iter.last = SType
 		iter.primed = true
@@ -218,7 +218,7 @@ func (iter *TypeMapIterator) Skip(n uint64) bool {
 		if !iter.impl.Skip(n - 1) {
 			return false
 		}
-		iter.last = iter.impl.Bit()
+		iter.last = iter.Type()
 		iter.primed = true
 		n = 1
 	}
@@ -249,7 +249,7 @@ func BuildTypeMap(text *Text, opts ...Option) (*TypeMap, error) {
 	if !baIter.Next() {
 		return nil, baIter.Close()
 	}
-	baIter.SetBit(SType)
+	baIter.SetValue(bitValue(SType))
 
 	if text.Len() == 0 {
 		if err := baIter.Close(); err != nil {
@@ -262,7 +262,7 @@ func BuildTypeMap(text *Text, opts ...Option) (*TypeMap, error) {
 	if !baIter.Next() {
 		return nil, baIter.Close()
 	}
-	baIter.SetBit(LType)
+	baIter.SetValue(bitValue(LType))
 
 	if text.Len() == 1 {
 		if err := baIter.Close(); err != nil {
@@ -297,7 +297,7 @@ func BuildTypeMap(text *Text, opts ...Option) (*TypeMap, error) {
 		} else {
 			bit = SType
 		}
-		baIter.SetBit(bit)
+		baIter.SetValue(bitValue(bit))
 		lastType = bit
 		lastSymbol = thisSymbol
 	}
@@ -0,0 +1,262 @@
+package suffixarray
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	bigarray "github.com/team-spectre/go-bigarray"
+)
+
+// OffsetUnit selects how text offsets are reported by rune-aware searches.
+type OffsetUnit byte
+
+const (
+	// RuneOffsets reports offsets as the number of runes preceding the
+	// match.  These are also the offsets used by the underlying Text.
+	RuneOffsets OffsetUnit = iota
+
+	// ByteOffsets reports offsets as the number of bytes preceding the
+	// match in the original UTF-8 input.
+	ByteOffsets
+)
+
+// RuneText is a Text whose symbols are Unicode code points, decoded from
+// UTF-8 input.
+//
+// Each distinct rune in the input is assigned a dense symbol number, so the
+// alphabet is only as large as the number of distinct runes actually used.
+// Symbols are numbered in code point order, which is also the order of their
+// UTF-8 encodings, so for valid UTF-8 input suffixes sort the same way they
+// would as raw bytes.  Suffixes of invalid input may not, since its bytes are
+// all decoded as the same rune, U+FFFD, rather than kept apart.
+// Because every offset in the Text is a rune boundary, searches can never
+// match in the middle of a multi-byte character.
+//
+// Invalid UTF-8 sequences are decoded as U+FFFD, one byte at a time.
+//
+type RuneText struct {
+	text    *Text
+	symbols *SymbolMap
+	offsets Storage
+}
+
+// NewTextFromUTF8 reads UTF-8 from r until EOF and constructs a RuneText.
+func NewTextFromUTF8(r io.Reader, opts ...Option) (*RuneText, error) {
+	runeOpts := extendOptions(
+		opts,
+		MaxValue(utf8.MaxRune),
+		WithFile(nil))
+
+	offsetOpts := extendOptions(
+		opts,
+		BytesPerValue(8),
+		WithFile(nil))
+
+	rawRunes, err := newArrayBuilder(runeOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer rawRunes.Close()
+
+	offsets, err := newArrayBuilder(offsetOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer offsets.Close()
+
+	seen := make(map[uint64]struct{})
+	br := bufio.NewReader(r)
+	byteOffset := uint64(0)
+	for {
+		ch, size, err := br.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		seen[uint64(ch)] = struct{}{}
+		if err := rawRunes.Append(uint64(ch)); err != nil {
+			return nil, err
+		}
+		if err := offsets.Append(byteOffset); err != nil {
+			return nil, err
+		}
+		byteOffset += uint64(size)
+	}
+	if err := offsets.Append(byteOffset); err != nil {
+		return nil, err
+	}
+
+	runes := make([]uint64, 0, len(seen))
+	for ch := range seen {
+		runes = append(runes, ch)
+	}
+	symbols := newSymbolMap(runes)
+
+	textOpts := extendOptions(
+		opts,
+		NumValues(rawRunes.Len()))
+
+	alphaSize := symbols.Len()
+	if alphaSize == 0 {
+		alphaSize = 1
+	}
+
+	text, err := NewText(alphaSize, textOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	needClose := true
+	defer func() {
+		if needClose {
+			text.Close()
+		}
+	}()
+
+	raw, err := rawRunes.Finish()
+	if err != nil {
+		return nil, err
+	}
+	defer raw.Close()
+
+	rawIter := raw.Iterate(0, raw.Len())
+	textIter := text.Iterate(0, text.Len())
+	for rawIter.Next() && textIter.Next() {
+		symbol, _ := symbols.Lookup(rawIter.Value())
+		textIter.SetSymbol(symbol)
+	}
+	if err := rawIter.Close(); err != nil {
+		textIter.Close()
+		return nil, err
+	}
+	if err := textIter.Close(); err != nil {
+		return nil, err
+	}
+
+	offsetArray, err := offsets.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	needClose = false
+	return &RuneText{
+		text:    text,
+		symbols: symbols,
+		offsets: offsetArray,
+	}, nil
+}
+
+// Text returns the underlying Text of rune symbols, suitable for passing to
+// BuildSuffixArray and friends.
+func (rt *RuneText) Text() *Text { return rt.text }
+
+// SymbolMap returns the mapping between the symbols of the Text and the runes
+// they represent.
+func (rt *RuneText) SymbolMap() *SymbolMap { return rt.symbols }
+
+// Len returns the length of the text in runes.
+func (rt *RuneText) Len() uint64 { return rt.text.Len() }
+
+// ByteLen returns the length of the original UTF-8 input in bytes.
+func (rt *RuneText) ByteLen() (uint64, error) {
+	return rt.offsets.ValueAt(rt.offsets.Len() - 1)
+}
+
+// RuneAt returns the rune at the given rune offset.
+func (rt *RuneText) RuneAt(index uint64) (rune, error) {
+	symbol, err := rt.text.SymbolAt(index)
+	if err != nil {
+		return 0, err
+	}
+	return rune(rt.symbols.Symbol(symbol)), nil
+}
+
+// ByteOffset converts a rune offset into the corresponding byte offset in the
+// original UTF-8 input.  Offset Len() maps to ByteLen().
+func (rt *RuneText) ByteOffset(index uint64) (uint64, error) {
+	return rt.offsets.ValueAt(index)
+}
+
+// Symbols converts a string into the equivalent list of symbols.  Returns
+// false if the string contains a rune which never appears in the text, in
+// which case the string cannot match anywhere.
+func (rt *RuneText) Symbols(str string) ([]uint64, bool) {
+	out := make([]uint64, 0, len(str))
+	for _, ch := range str {
+		symbol, ok := rt.symbols.Lookup(uint64(ch))
+		if !ok {
+			return nil, false
+		}
+		out = append(out, symbol)
+	}
+	return out, true
+}
+
+// Close frees the resources used by the RuneText, including its Text.
+func (rt *RuneText) Close() error {
+	err := rt.text.Close()
+	if err2 := rt.offsets.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// Debug returns a human-friendly debugging representation of the RuneText.
+func (rt *RuneText) Debug() string {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	err := rt.text.ForEach(func(index uint64, symbol uint64) error {
+		if index > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%q", rune(rt.symbols.Symbol(symbol)))
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	buf.WriteByte(']')
+	return buf.String()
+}
+
+// SearchRunes searches a RuneText for the given UTF-8 phrase, returning the
+// matching offsets in the requested unit.  The suffix array and LCP-LR array
+// must have been built from rt.Text().
+func SearchRunes(rt *RuneText, sa *SuffixArray, lcplr bigarray.BigArray, phrase string, unit OffsetUnit) ([]uint64, error) {
+	symbols, ok := rt.Symbols(phrase)
+	if !ok {
+		return nil, nil
+	}
+
+	results, err := searchSymbols(rt.text, sa, bigArrayToStorage(lcplr), symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	if unit == ByteOffsets {
+		for i, pos := range results {
+			results[i], err = rt.ByteOffset(pos)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return results, nil
+}
+
+// CountRunes returns the number of occurrences of the given UTF-8 phrase in a
+// RuneText.
+func CountRunes(rt *RuneText, sa *SuffixArray, lcplr bigarray.BigArray, phrase string) (uint64, error) {
+	symbols, ok := rt.Symbols(phrase)
+	if !ok {
+		return 0, nil
+	}
+
+	lo, hi, err := rangeSymbols(rt.text, sa, bigArrayToStorage(lcplr), symbols)
+	return hi - lo, err
+}
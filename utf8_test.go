@@ -0,0 +1,153 @@
+package suffixarray
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const multilingualText = `日本語のテキスト。English text. 日本の本。Ελληνικά κείμενα. 本本本`
+
+func TestRuneText_Search(t *testing.T) {
+	type testrow struct {
+		Phrase string
+		Unit   OffsetUnit
+	}
+
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		rt, err := NewTextFromUTF8(strings.NewReader(multilingualText), opts...)
+		if err != nil {
+			t.Errorf("[%s] NewTextFromUTF8: error: %v", cfg.Name, err)
+			continue
+		}
+
+		if expected := uint64(utf8.RuneCountInString(multilingualText)); rt.Len() != expected {
+			t.Errorf("[%s] Len: expected %d, got %d", cfg.Name, expected, rt.Len())
+		}
+		if n, err := rt.ByteLen(); err != nil {
+			t.Errorf("[%s] ByteLen: error: %v", cfg.Name, err)
+		} else if expected := uint64(len(multilingualText)); n != expected {
+			t.Errorf("[%s] ByteLen: expected %d, got %d", cfg.Name, expected, n)
+		}
+
+		sa, err := BuildSuffixArray(rt.Text(), opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildSuffixArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		lcp, err := BuildLCPArray(rt.Text(), sa, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildLCPArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		lcplr, err := BuildLCPLRArray(lcp, opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildLCPLRArray: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for i, row := range []testrow{
+			testrow{"本", ByteOffsets},
+			testrow{"本", RuneOffsets},
+			testrow{"日本", ByteOffsets},
+			testrow{"text", ByteOffsets},
+			testrow{"κείμενα", RuneOffsets},
+			testrow{"本本", RuneOffsets},
+			testrow{"中文", ByteOffsets},
+			testrow{"\xe6\x9c", ByteOffsets},
+			testrow{"日本語のテクスト", RuneOffsets},
+			testrow{"English texts", ByteOffsets},
+			testrow{"本本本本", RuneOffsets},
+			testrow{"Ελληνικό", ByteOffsets},
+		} {
+			expectedList := NaiveSearch(multilingualText, row.Phrase)
+			if !utf8.ValidString(row.Phrase) {
+				expectedList = nil
+			}
+			if row.Unit == RuneOffsets {
+				for j, pos := range expectedList {
+					expectedList[j] = uint64(utf8.RuneCountInString(multilingualText[:pos]))
+				}
+			}
+
+			offsets, err := SearchRunes(rt, sa, lcplr, row.Phrase, row.Unit)
+			if err != nil {
+				t.Errorf("[%s/%03d] SearchRunes %q: error: %v", cfg.Name, i, row.Phrase, err)
+				continue
+			}
+
+			expected := fmt.Sprintf("%v", expectedList)
+			actual := fmt.Sprintf("%v", offsets)
+			if expected != actual {
+				t.Errorf("[%s/%03d] SearchRunes %q: expected %s, got %s", cfg.Name, i, row.Phrase, expected, actual)
+			}
+
+			count, err := CountRunes(rt, sa, lcplr, row.Phrase)
+			if err != nil {
+				t.Errorf("[%s/%03d] CountRunes %q: error: %v", cfg.Name, i, row.Phrase, err)
+				continue
+			}
+			if count != uint64(len(expectedList)) {
+				t.Errorf("[%s/%03d] CountRunes %q: expected %d, got %d", cfg.Name, i, row.Phrase, len(expectedList), count)
+			}
+		}
+
+		if err := rt.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
+
+func TestRuneText_Debug(t *testing.T) {
+	rt, err := NewTextFromUTF8(strings.NewReader("añ本"))
+	if err != nil {
+		t.Fatalf("NewTextFromUTF8: error: %v", err)
+	}
+	defer rt.Close()
+
+	expected := `['a' 'ñ' '本']`
+	actual := rt.Debug()
+	if expected != actual {
+		t.Errorf("Debug: expected %s, got %s", expected, actual)
+	}
+}
+
+func TestNewTextFromUTF8_Large(t *testing.T) {
+	input := strings.Repeat(multilingualText, 50)
+	for _, cfg := range configurations {
+		rt, err := NewTextFromUTF8(strings.NewReader(input), cfg.Opts...)
+		if err != nil {
+			t.Errorf("[%s] NewTextFromUTF8: error: %v", cfg.Name, err)
+			continue
+		}
+
+		runeIndex := uint64(0)
+		for byteIndex, ch := range input {
+			actualRune, err := rt.RuneAt(runeIndex)
+			if err != nil {
+				t.Errorf("[%s] RuneAt %d: error: %v", cfg.Name, runeIndex, err)
+				break
+			}
+			actualOffset, err := rt.ByteOffset(runeIndex)
+			if err != nil {
+				t.Errorf("[%s] ByteOffset %d: error: %v", cfg.Name, runeIndex, err)
+				break
+			}
+			if actualRune != ch || actualOffset != uint64(byteIndex) {
+				t.Errorf("[%s] rune %d: expected %q at %d, got %q at %d", cfg.Name, runeIndex, ch, byteIndex, actualRune, actualOffset)
+				break
+			}
+			runeIndex++
+		}
+
+		if err := rt.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
@@ -1,10 +1,5 @@
 package suffixarray
 
-import (
-	bigarray "github.com/team-spectre/go-bigarray"
-	bigbitvector "github.com/team-spectre/go-bigbitvector"
-)
-
 func extendOptions(original []Option, more ...Option) []Option {
 	m := uint(len(original))
 	n := uint(len(more))
@@ -14,22 +9,127 @@ func extendOptions(original []Option, more ...Option) []Option {
 	return dupe
 }
 
-func makeBigArray(list []Option) (bigarray.BigArray, error) {
-	out := make([]bigarray.Option, 0, len(list))
+func makeBuildOptions(list []Option) buildOptions {
+	var o buildOptions
 	for _, item := range list {
-		if item.BigArrayOption != nil {
-			out = append(out, item.BigArrayOption)
+		if item.buildOption != nil {
+			item.buildOption(&o)
 		}
 	}
-	return bigarray.New(out...)
+	return o
 }
 
-func makeBigBitVector(list []Option) (bigbitvector.BigBitVector, error) {
-	out := make([]bigbitvector.Option, 0, len(list))
+func makeStorageOptions(list []Option) storageOptions {
+	var o storageOptions
 	for _, item := range list {
-		if item.BigBitVectorOption != nil {
-			out = append(out, item.BigBitVectorOption)
+		if item.storageOption != nil {
+			item.storageOption(&o)
+		}
+	}
+	return o
+}
+
+// arrayBuilder accumulates values into a big array whose final length is not
+// known in advance, such as when reading from an io.Reader.  The array's
+// capacity is doubled whenever it fills up.
+type arrayBuilder struct {
+	opts       []Option
+	newStorage func([]Option) (Storage, error)
+	ba         Storage
+	iter       StorageIterator
+	n          uint64
+}
+
+func newArrayBuilder(opts []Option) (*arrayBuilder, error) {
+	return newArrayBuilderWith(opts, makeStorage)
+}
+
+// newArrayBuilderWith is like newArrayBuilder, but constructs each array by
+// calling fn, such as makeTextStorage.
+func newArrayBuilderWith(opts []Option, fn func([]Option) (Storage, error)) (*arrayBuilder, error) {
+	ba, err := fn(extendOptions(opts, NumValues(1024)))
+	if err != nil {
+		return nil, err
+	}
+	return &arrayBuilder{
+		opts:       opts,
+		newStorage: fn,
+		ba:         ba,
+		iter:       ba.Iterate(0, ba.Len()),
+	}, nil
+}
+
+// Len returns the number of values appended so far.
+func (b *arrayBuilder) Len() uint64 { return b.n }
+
+// Append adds a value to the end of the array.
+func (b *arrayBuilder) Append(value uint64) error {
+	if !b.iter.Next() {
+		if err := b.grow(); err != nil {
+			return err
+		}
+		if !b.iter.Next() {
+			return b.iter.Err()
 		}
 	}
-	return bigbitvector.New(out...)
+	b.iter.SetValue(value)
+	b.n++
+	return nil
+}
+
+func (b *arrayBuilder) grow() error {
+	if err := b.iter.Close(); err != nil {
+		return err
+	}
+
+	ba, err := b.newStorage(extendOptions(b.opts, NumValues(2*b.ba.Len())))
+	if err != nil {
+		return err
+	}
+
+	src := b.ba.Iterate(0, b.n)
+	dst := ba.Iterate(0, b.n)
+	for src.Next() && dst.Next() {
+		dst.SetValue(src.Value())
+	}
+	err = src.Close()
+	if err2 := dst.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		ba.Close()
+		return err
+	}
+
+	b.ba.Close()
+	b.ba = ba
+	b.iter = ba.Iterate(b.n, ba.Len())
+	return nil
+}
+
+// Finish trims the array to the number of values appended and returns it.
+// The builder must not be used afterward.
+func (b *arrayBuilder) Finish() (Storage, error) {
+	ba := b.ba
+	b.ba = nil
+	if err := b.iter.Close(); err != nil {
+		ba.Close()
+		return nil, err
+	}
+	if err := ba.Truncate(b.n); err != nil {
+		ba.Close()
+		return nil, err
+	}
+	return ba, nil
+}
+
+// Close frees the array if Finish has not been called.
+func (b *arrayBuilder) Close() error {
+	if b.ba == nil {
+		return nil
+	}
+	b.iter.Close()
+	err := b.ba.Close()
+	b.ba = nil
+	return err
 }
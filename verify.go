@@ -0,0 +1,168 @@
+package suffixarray
+
+import (
+	"fmt"
+)
+
+// VerificationError is returned by VerifySuffixArray and VerifyLCPArray to
+// describe the first inconsistency found.
+type VerificationError struct {
+	// Array is "SA" or "LCP", naming the array which failed verification.
+	Array string
+
+	// Index is the index in that array at which the problem was found.
+	Index uint64
+
+	// Reason is a human-readable description of the problem.
+	Reason string
+}
+
+// Error fulfills the error interface.
+func (err *VerificationError) Error() string {
+	return fmt.Sprintf("%s[%d]: %s", err.Array, err.Index, err.Reason)
+}
+
+// VerifySuffixArray checks that sa is the correct suffix array for text,
+// returning a *VerificationError that pinpoints the first bad index if not.
+//
+// The check runs in O(n) time, without comparing whole suffixes: once sa is
+// known to be a permutation of [0, n] with SA[0] = n, it is correct iff for
+// every pair of adjacent suffixes i = SA[k-1] and j = SA[k], either
+// TEXT[i] < TEXT[j], or TEXT[i] = TEXT[j] and the suffix i+1 precedes the
+// suffix j+1 in sa.
+//
+// Reference:
+//
+//  [1] “Fast Lightweight Suffix Array Construction and Checking”,
+//      Stefan Burkhardt and Juha Kärkkäinen.
+//      https://doi.org/10.1007/3-540-44888-8_5
+//
+func VerifySuffixArray(text *Text, sa *SuffixArray, opts ...Option) error {
+	n := text.Len()
+	if sa.Len() != n+1 {
+		return &VerificationError{"SA", sa.Len(), fmt.Sprintf("length is %d, expected %d for text of length %d", sa.Len(), n+1, n)}
+	}
+
+	seenOpts := extendOptions(
+		opts,
+		NumValues(n+1),
+		WithFile(nil))
+
+	seen, err := makeBitStorage(seenOpts)
+	if err != nil {
+		return err
+	}
+	defer seen.Close()
+
+	rankOpts := extendOptions(
+		opts,
+		NumValues(n+1),
+		BytesPerValue(8),
+		WithFile(nil))
+
+	rankArray, err := makeStorage(rankOpts)
+	if err != nil {
+		return err
+	}
+	defer rankArray.Close()
+
+	err = sa.ForEach(func(index uint64, pos uint64) error {
+		if index == 0 && pos != n {
+			return &VerificationError{"SA", index, fmt.Sprintf("is %d, expected the empty suffix %d", pos, n)}
+		}
+		if pos > n {
+			return &VerificationError{"SA", index, fmt.Sprintf("is %d, which is out of range for text of length %d", pos, n)}
+		}
+		dupe, err := seen.ValueAt(pos)
+		if err != nil {
+			return err
+		}
+		if dupe != 0 {
+			return &VerificationError{"SA", index, fmt.Sprintf("is %d, which appears more than once", pos)}
+		}
+		if err := seen.SetValueAt(pos, 1); err != nil {
+			return err
+		}
+		return rankArray.SetValueAt(pos, index)
+	})
+	if err != nil {
+		return err
+	}
+
+	var prev uint64
+	return sa.ForEach(func(index uint64, pos uint64) error {
+		last := prev
+		prev = pos
+		if index < 2 {
+			return nil
+		}
+
+		symPrev, err := text.SymbolAt(last)
+		if err != nil {
+			return err
+		}
+		symThis, err := text.SymbolAt(pos)
+		if err != nil {
+			return err
+		}
+
+		if symPrev > symThis {
+			return &VerificationError{"SA", index, fmt.Sprintf("suffix %d starts with symbol %d, which sorts before the previous suffix %d starting with symbol %d", pos, symThis, last, symPrev)}
+		}
+		if symPrev < symThis {
+			return nil
+		}
+
+		rankPrev, err := rankArray.ValueAt(last + 1)
+		if err != nil {
+			return err
+		}
+		rankThis, err := rankArray.ValueAt(pos + 1)
+		if err != nil {
+			return err
+		}
+
+		if rankPrev > rankThis {
+			return &VerificationError{"SA", index, fmt.Sprintf("suffix %d sorts before the previous suffix %d", pos, last)}
+		}
+		return nil
+	})
+}
+
+// VerifyLCPArray checks that lcp is the correct LCP array for text and sa,
+// returning a *VerificationError that pinpoints the first bad index if not.
+// The suffix array itself is assumed to be correct; see VerifySuffixArray.
+//
+// The check runs in O(n) time, by recomputing the LCP array with Kasai's
+// algorithm and comparing the two.
+func VerifyLCPArray(text *Text, sa *SuffixArray, lcp *LCPArray, opts ...Option) error {
+	if lcp.Len() != sa.Len() {
+		return &VerificationError{"LCP", lcp.Len(), fmt.Sprintf("length is %d, expected %d", lcp.Len(), sa.Len())}
+	}
+
+	opts = extendOptions(
+		opts,
+		WithFile(nil))
+
+	expected, err := BuildLCPArray(text, sa, opts...)
+	if err != nil {
+		return err
+	}
+	defer expected.Close()
+
+	expectedIter := expected.Iterate(1, expected.Len())
+	actualIter := lcp.Iterate(1, lcp.Len())
+	for expectedIter.Next() && actualIter.Next() {
+		if expectedIter.Height() != actualIter.Height() {
+			err = &VerificationError{"LCP", actualIter.Index(), fmt.Sprintf("is %d, expected %d", actualIter.Height(), expectedIter.Height())}
+			break
+		}
+	}
+	if err2 := expectedIter.Close(); err == nil {
+		err = err2
+	}
+	if err2 := actualIter.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
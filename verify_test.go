@@ -0,0 +1,79 @@
+package suffixarray
+
+import (
+	"testing"
+)
+
+func TestVerifySuffixArray(t *testing.T) {
+	type testrow struct {
+		Input    string
+		SA       string
+		Expected string
+	}
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		for i, row := range []testrow{
+			testrow{banana, bananaSA, ""},
+			testrow{banana2, banana2SA, ""},
+			testrow{cabbage, cabbageSA, ""},
+			testrow{loremIpsum, loremIpsumSA, ""},
+			testrow{abcdefgh, abcdefghSA, ""},
+			testrow{aaaaaaaa, aaaaaaaaSA, ""},
+			testrow{banana, `[6 5 3 1 0 4]`, "SA[6]: length is 6, expected 7 for text of length 6"},
+			testrow{banana, `[5 6 3 1 0 4 2]`, "SA[0]: is 5, expected the empty suffix 6"},
+			testrow{banana, `[6 5 3 1 0 4 7]`, "SA[6]: is 7, which is out of range for text of length 6"},
+			testrow{banana, `[6 5 3 1 0 4 4]`, "SA[6]: is 4, which appears more than once"},
+			testrow{banana, `[6 5 3 1 4 0 2]`, "SA[5]: suffix 0 starts with symbol 98, which sorts before the previous suffix 4 starting with symbol 110"},
+			testrow{banana, `[6 5 1 3 0 4 2]`, "SA[3]: suffix 3 sorts before the previous suffix 1"},
+			testrow{banana, `[6 3 5 1 0 4 2]`, "SA[2]: suffix 5 sorts before the previous suffix 3"},
+		} {
+			text := NewTextFromString(row.Input, opts...)
+			sa := NewFromString(row.SA, opts...)
+
+			actual := ""
+			if err := VerifySuffixArray(text, sa, opts...); err != nil {
+				actual = err.Error()
+			}
+			if row.Expected != actual {
+				t.Errorf("[%s/%03d] VerifySuffixArray %q %s: expected %q, got %q", cfg.Name, i, row.Input, row.SA, row.Expected, actual)
+			}
+		}
+	}
+}
+
+func TestVerifyLCPArray(t *testing.T) {
+	type testrow struct {
+		Input    string
+		SA       string
+		LCP      string
+		Expected string
+	}
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		for i, row := range []testrow{
+			testrow{banana, bananaSA, bananaLCP, ""},
+			testrow{banana2, banana2SA, banana2LCP, ""},
+			testrow{loremIpsum, loremIpsumSA, loremIpsumLCP, ""},
+			testrow{aaaaaaaa, aaaaaaaaSA, aaaaaaaaLCP, ""},
+			testrow{banana, bananaSA, `[. 0 1 3 0 0]`, "LCP[6]: length is 6, expected 7"},
+			testrow{banana, bananaSA, `[. 0 1 2 0 0 2]`, "LCP[3]: is 2, expected 3"},
+			testrow{aaaaaaaa, aaaaaaaaSA, `[. 0 1 2 3 4 5 6 8]`, "LCP[8]: is 8, expected 7"},
+		} {
+			text := NewTextFromString(row.Input, opts...)
+			sa := NewFromString(row.SA, opts...)
+			lcp := NewLCPArrayFromString(row.LCP, opts...)
+
+			actual := ""
+			if err := VerifyLCPArray(text, sa, lcp, opts...); err != nil {
+				actual = err.Error()
+			}
+			if row.Expected != actual {
+				t.Errorf("[%s/%03d] VerifyLCPArray %q %s: expected %q, got %q", cfg.Name, i, row.Input, row.LCP, row.Expected, actual)
+			}
+		}
+	}
+}
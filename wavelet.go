@@ -0,0 +1,326 @@
+package suffixarray
+
+import (
+	"math/bits"
+)
+
+// waveletMatrix answers two-dimensional range queries over a sequence of
+// values, such as "which values in [a, b) appear at indices [lo, hi)?", in
+// time proportional to the number of values reported.
+//
+// Each level holds one bit of every value, most significant first, with the
+// values stably partitioned by the bits of all previous levels: those with a
+// 0 bit first, then those with a 1 bit.  Following a range of indices from
+// one level to the next takes two rank queries, so narrowing a range by one
+// bit of the value is O(1).
+//
+// The bits of each level are kept as rankedBits.  Queries only call ValueAt,
+// so they are safe to run concurrently.
+//
+type waveletMatrix struct {
+	n      uint64
+	levels []waveletLevel
+}
+
+type waveletLevel struct {
+	rankedBits
+	zeros uint64
+}
+
+// rankedBits is a sequence of bits which supports rank queries: the number of
+// 1 bits before any index.  The bits are packed 64 to a word, alongside a
+// running count of the 1 bits preceding each word.
+type rankedBits struct {
+	words Storage
+	ones  Storage
+}
+
+// rankedBitsWriter appends bits in order to a new rankedBits.
+type rankedBitsWriter struct {
+	rb       rankedBits
+	wordIter StorageIterator
+	onesIter StorageIterator
+	word     uint64
+	total    uint64
+	n        uint64
+}
+
+func newRankedBitsWriter(n uint64, opts []Option) (*rankedBitsWriter, error) {
+	numWords := (n + 63) / 64
+	words, err := makeStorage(extendOptions(
+		opts,
+		NumValues(numWords),
+		BytesPerValue(8),
+		WithFile(nil)))
+	if err != nil {
+		return nil, err
+	}
+	ones, err := makeStorage(extendOptions(
+		opts,
+		NumValues(numWords+1),
+		MaxValue(n+1),
+		WithFile(nil)))
+	if err != nil {
+		words.Close()
+		return nil, err
+	}
+	return &rankedBitsWriter{
+		rb:       rankedBits{words, ones},
+		wordIter: words.Iterate(0, words.Len()),
+		onesIter: ones.Iterate(0, ones.Len()),
+	}, nil
+}
+
+// Append adds the next bit.
+func (w *rankedBitsWriter) Append(bit bool) {
+	if bit {
+		w.word |= 1 << (w.n % 64)
+	}
+	w.n++
+	if w.n%64 == 0 {
+		w.flush()
+	}
+}
+
+func (w *rankedBitsWriter) flush() {
+	if w.wordIter.Next() && w.onesIter.Next() {
+		w.wordIter.SetValue(w.word)
+		w.onesIter.SetValue(w.total)
+		w.total += uint64(bits.OnesCount64(w.word))
+		w.word = 0
+	}
+}
+
+// Finish completes the rankedBits and returns it along with its number of 1
+// bits.  On error, the rankedBits is closed.
+func (w *rankedBitsWriter) Finish() (rankedBits, uint64, error) {
+	if w.n%64 != 0 {
+		w.flush()
+	}
+	if w.onesIter.Next() {
+		w.onesIter.SetValue(w.total)
+	}
+	err := w.wordIter.Close()
+	if err2 := w.onesIter.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		w.rb.Close()
+		return rankedBits{}, 0, err
+	}
+	return w.rb, w.total, nil
+}
+
+// rank1 returns the number of 1 bits at indices [0, i).
+func (rb *rankedBits) rank1(i uint64) (uint64, error) {
+	before, err := rb.ones.ValueAt(i / 64)
+	if err != nil || i%64 == 0 {
+		return before, err
+	}
+	word, err := rb.words.ValueAt(i / 64)
+	if err != nil {
+		return 0, err
+	}
+	mask := uint64(1)<<(i%64) - 1
+	return before + uint64(bits.OnesCount64(word&mask)), nil
+}
+
+// bitAt returns the bit at index i.
+func (rb *rankedBits) bitAt(i uint64) (bool, error) {
+	word, err := rb.words.ValueAt(i / 64)
+	return word&(1<<(i%64)) != 0, err
+}
+
+// Close frees the resources used by the rankedBits.
+func (rb *rankedBits) Close() error {
+	err := rb.words.Close()
+	if err2 := rb.ones.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// buildWaveletMatrix constructs the waveletMatrix of the values in src, none
+// of which may exceed maxValue.
+func buildWaveletMatrix(src Storage, maxValue uint64, opts []Option) (*waveletMatrix, error) {
+	n := src.Len()
+	numBits := bits.Len64(maxValue)
+	if numBits == 0 {
+		numBits = 1
+	}
+
+	wm := &waveletMatrix{n: n, levels: make([]waveletLevel, 0, numBits)}
+	seqOpts := extendOptions(
+		opts,
+		NumValues(n),
+		MaxValue(maxValue),
+		WithFile(nil))
+
+	cur := src
+	closeCur := func() {
+		if cur != src {
+			cur.Close()
+		}
+	}
+	defer func() { closeCur() }()
+
+	for level := 0; level < numBits; level++ {
+		shift := uint(numBits - 1 - level)
+
+		rb, ones, err := packLevel(cur, shift, opts)
+		if err != nil {
+			wm.Close()
+			return nil, err
+		}
+		wm.levels = append(wm.levels, waveletLevel{rb, n - ones})
+
+		if level == numBits-1 {
+			break
+		}
+
+		// Stably partition the values by this level's bit for the next.
+		next, err := makeStorage(seqOpts)
+		if err != nil {
+			wm.Close()
+			return nil, err
+		}
+		dst := next.Iterate(0, n)
+		for _, bit := range []uint64{0, 1} {
+			srcIter := cur.Iterate(0, n)
+			for srcIter.Next() {
+				value := srcIter.Value()
+				if (value>>shift)&1 == bit && dst.Next() {
+					dst.SetValue(value)
+				}
+			}
+			if err = srcIter.Close(); err != nil {
+				break
+			}
+		}
+		if err2 := dst.Close(); err == nil {
+			err = err2
+		}
+		if err != nil {
+			next.Close()
+			wm.Close()
+			return nil, err
+		}
+
+		closeCur()
+		cur = next
+	}
+	return wm, nil
+}
+
+// packLevel collects bit shift of each value in cur, and returns the bits
+// along with the number of 1 bits.
+func packLevel(cur Storage, shift uint, opts []Option) (rankedBits, uint64, error) {
+	w, err := newRankedBitsWriter(cur.Len(), opts)
+	if err != nil {
+		return rankedBits{}, 0, err
+	}
+	err = forEachValue(cur, func(index uint64, value uint64) error {
+		w.Append((value>>shift)&1 != 0)
+		return nil
+	})
+	rb, ones, err2 := w.Finish()
+	if err == nil {
+		err = err2
+	} else if err2 == nil {
+		rb.Close()
+	}
+	return rb, ones, err
+}
+
+// descend maps the index range [lo, hi) of the level to the corresponding
+// ranges of the next level for the values with a 0 bit and a 1 bit.
+func (wl *waveletLevel) descend(lo, hi uint64) (lo0, hi0, lo1, hi1 uint64, err error) {
+	ones1, err := wl.rank1(lo)
+	if err != nil {
+		return
+	}
+	ones2, err := wl.rank1(hi)
+	if err != nil {
+		return
+	}
+	lo0, hi0 = lo-ones1, hi-ones2
+	lo1, hi1 = wl.zeros+ones1, wl.zeros+ones2
+	return
+}
+
+// Count returns the number of values in [a, b) at indices [lo, hi).
+func (wm *waveletMatrix) Count(lo, hi, a, b uint64) (uint64, error) {
+	return wm.count(0, 0, lo, hi, a, b)
+}
+
+func (wm *waveletMatrix) count(level int, prefix, lo, hi, a, b uint64) (uint64, error) {
+	if lo >= hi {
+		return 0, nil
+	}
+	first, last := wm.valueBounds(level, prefix)
+	if last < a || first >= b {
+		return 0, nil
+	}
+	if first >= a && last < b {
+		return hi - lo, nil
+	}
+
+	lo0, hi0, lo1, hi1, err := wm.levels[level].descend(lo, hi)
+	if err != nil {
+		return 0, err
+	}
+	count0, err := wm.count(level+1, prefix<<1, lo0, hi0, a, b)
+	if err != nil {
+		return 0, err
+	}
+	count1, err := wm.count(level+1, prefix<<1|1, lo1, hi1, a, b)
+	return count0 + count1, err
+}
+
+// Report calls fn, in ascending order, with each value in [a, b) at indices
+// [lo, hi) and the number of times it occurs there.
+func (wm *waveletMatrix) Report(lo, hi, a, b uint64, fn func(value, count uint64) error) error {
+	return wm.report(0, 0, lo, hi, a, b, fn)
+}
+
+func (wm *waveletMatrix) report(level int, prefix, lo, hi, a, b uint64, fn func(uint64, uint64) error) error {
+	if lo >= hi {
+		return nil
+	}
+	first, last := wm.valueBounds(level, prefix)
+	if last < a || first >= b {
+		return nil
+	}
+	if level == len(wm.levels) {
+		return fn(prefix, hi-lo)
+	}
+
+	lo0, hi0, lo1, hi1, err := wm.levels[level].descend(lo, hi)
+	if err != nil {
+		return err
+	}
+	if err := wm.report(level+1, prefix<<1, lo0, hi0, a, b, fn); err != nil {
+		return err
+	}
+	return wm.report(level+1, prefix<<1|1, lo1, hi1, a, b, fn)
+}
+
+// valueBounds returns the smallest and largest values which begin with the
+// given prefix of level bits.
+func (wm *waveletMatrix) valueBounds(level int, prefix uint64) (uint64, uint64) {
+	shift := uint(len(wm.levels) - level)
+	first := prefix << shift
+	return first, first | (uint64(1)<<shift - 1)
+}
+
+// Close frees the resources used by the waveletMatrix.
+func (wm *waveletMatrix) Close() error {
+	var finalError error
+	for _, wl := range wm.levels {
+		if err := wl.rankedBits.Close(); err != nil && finalError == nil {
+			finalError = err
+		}
+	}
+	wm.levels = nil
+	return finalError
+}
@@ -0,0 +1,60 @@
+package suffixarray
+
+// SearchInRange returns the offsets of the occurrences of phrase which lie
+// entirely within the text offsets [a, b), in ascending order.
+//
+// The occurrences of the phrase form one range of the suffix array, and the
+// wanted ones are those whose offset falls in [a, b-len(phrase)].  A wavelet
+// matrix over the suffix array answers that two-dimensional query directly,
+// so the cost is proportional to the number of results within the window
+// (times log n) rather than to the total number of occurrences.  The wavelet
+// matrix is built on the first call and kept by the Index.
+//
+func SearchInRange(idx *Index, phrase string, a, b uint64) ([]uint64, error) {
+	var results []uint64
+	err := searchInRange(idx, phrase, a, b, func(wm *waveletMatrix, lo, hi, first, last uint64) error {
+		return wm.Report(lo, hi, first, last, func(value, count uint64) error {
+			results = append(results, value)
+			return nil
+		})
+	})
+	return results, err
+}
+
+// CountInRange returns the number of occurrences of phrase which lie entirely
+// within the text offsets [a, b), as SearchInRange would find, in O(log n)
+// time once the phrase has been found.
+func CountInRange(idx *Index, phrase string, a, b uint64) (uint64, error) {
+	var count uint64
+	err := searchInRange(idx, phrase, a, b, func(wm *waveletMatrix, lo, hi, first, last uint64) error {
+		var err error
+		count, err = wm.Count(lo, hi, first, last)
+		return err
+	})
+	return count, err
+}
+
+// searchInRange finds the suffix array range of phrase and the range of
+// offsets [first, last) at which an occurrence lies within [a, b), and passes
+// them to fn if neither is empty.
+func searchInRange(idx *Index, phrase string, a, b uint64, fn func(wm *waveletMatrix, lo, hi, first, last uint64) error) error {
+	symbols := idx.prepare(stringToSymbols(phrase))
+	m := uint64(len(symbols))
+	if b > idx.Len() {
+		b = idx.Len()
+	}
+	if a > b || b-a < m {
+		return nil
+	}
+
+	lo, hi, err := rangeSymbols(idx.searchText(), idx.sa, idx.lcplr, symbols)
+	if err != nil || lo == hi {
+		return err
+	}
+
+	wm, err := idx.waveletMatrix()
+	if err != nil {
+		return err
+	}
+	return fn(wm, lo, hi, a, b-m+1)
+}
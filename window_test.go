@@ -0,0 +1,145 @@
+package suffixarray
+
+import (
+	"fmt"
+	"testing"
+)
+
+func NaiveSearchInRange(text, phrase string, a, b uint64) []uint64 {
+	var out []uint64
+	for _, pos := range NaiveSearch(text, phrase) {
+		if pos >= a && pos+uint64(len(phrase)) <= b {
+			out = append(out, pos)
+		}
+	}
+	return out
+}
+
+func TestSearchInRange(t *testing.T) {
+	phrases := []string{searchPhrase, "Lorem", "a", " ", "\n", "zzz", "sit amet", "us", "sit amex", "Phasellus nex", "t amet, consectetur adipiscing elix"}
+	n := uint64(len(sampleText))
+	windows := [][2]uint64{
+		{0, n},
+		{0, 0},
+		{0, 100},
+		{100, 101},
+		{250, 900},
+		{n / 2, n + 10},
+		{n - 3, n},
+		{900, 250},
+	}
+
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		idx, err := BuildIndex(NewTextFromString(sampleText, opts...), opts...)
+		if err != nil {
+			t.Errorf("[%s] BuildIndex: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for _, phrase := range phrases {
+			for _, w := range windows {
+				a, b := w[0], w[1]
+				naive := NaiveSearchInRange(sampleText, phrase, a, b)
+				expected := fmt.Sprintf("%v", naive)
+
+				offsets, err := SearchInRange(idx, phrase, a, b)
+				if err != nil {
+					t.Errorf("[%s] SearchInRange %q [%d, %d): error: %v", cfg.Name, phrase, a, b, err)
+					continue
+				}
+				if actual := fmt.Sprintf("%v", offsets); expected != actual {
+					t.Errorf("[%s] SearchInRange %q [%d, %d): expected %s, got %s", cfg.Name, phrase, a, b, expected, actual)
+				}
+
+				count, err := CountInRange(idx, phrase, a, b)
+				if err != nil || count != uint64(len(naive)) {
+					t.Errorf("[%s] CountInRange %q [%d, %d): expected %d, got %d, %v", cfg.Name, phrase, a, b, len(naive), count, err)
+				}
+			}
+		}
+
+		if err := idx.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}
+
+func TestWaveletMatrix(t *testing.T) {
+	values := []uint64{5, 1, 1, 7, 0, 3, 5, 5, 2, 6, 0, 7}
+	for i := 0; i < 200; i++ {
+		values = append(values, uint64(i*37%101))
+	}
+
+	for _, cfg := range configurations {
+		t.Logf("starting %s tests", cfg.Name)
+		opts := cfg.Opts
+
+		ba, err := makeStorage(extendOptions(opts, NumValues(uint64(len(values))), MaxValue(100)))
+		if err != nil {
+			t.Errorf("[%s] makeStorage: error: %v", cfg.Name, err)
+			continue
+		}
+		iter := ba.Iterate(0, ba.Len())
+		for iter.Next() {
+			iter.SetValue(values[iter.Index()])
+		}
+		if err := iter.Close(); err != nil {
+			t.Errorf("[%s] SetValue: error: %v", cfg.Name, err)
+			continue
+		}
+
+		wm, err := buildWaveletMatrix(ba, 100, opts)
+		if err != nil {
+			t.Errorf("[%s] buildWaveletMatrix: error: %v", cfg.Name, err)
+			continue
+		}
+
+		for _, q := range [][4]uint64{
+			{0, 12, 0, 8},
+			{1, 8, 1, 6},
+			{3, 3, 0, 100},
+			{0, 212, 50, 60},
+			{20, 150, 0, 101},
+			{5, 200, 99, 200},
+		} {
+			lo, hi, a, b := q[0], q[1], q[2], q[3]
+			counts := make(map[uint64]uint64)
+			var total uint64
+			for _, value := range values[lo:hi] {
+				if value >= a && value < b {
+					counts[value]++
+					total++
+				}
+			}
+
+			var expected, actual string
+			for value := a; value < b && value <= 100; value++ {
+				if counts[value] > 0 {
+					expected += fmt.Sprintf("%d:%d ", value, counts[value])
+				}
+			}
+			err := wm.Report(lo, hi, a, b, func(value, count uint64) error {
+				actual += fmt.Sprintf("%d:%d ", value, count)
+				return nil
+			})
+			if err != nil || expected != actual {
+				t.Errorf("[%s] Report %v: expected %q, got %q, %v", cfg.Name, q, expected, actual, err)
+			}
+
+			count, err := wm.Count(lo, hi, a, b)
+			if err != nil || count != total {
+				t.Errorf("[%s] Count %v: expected %d, got %d, %v", cfg.Name, q, total, count, err)
+			}
+		}
+
+		if err := wm.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+		if err := ba.Close(); err != nil {
+			t.Errorf("[%s] Close: error: %v", cfg.Name, err)
+		}
+	}
+}